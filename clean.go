@@ -0,0 +1,754 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CleanMode selects how cleanup removes rows
+type CleanMode string
+
+const (
+	// CleanTruncate issues a single TRUNCATE ... CASCADE (the default)
+	CleanTruncate CleanMode = "truncate"
+
+	// CleanDelete issues DELETE in foreign key order. It avoids the ACCESS EXCLUSIVE locks
+	// taken by TRUNCATE and is usually faster on many small tables. Each reference cycle needs
+	// a DEFERRABLE foreign key other than ON DELETE RESTRICT.
+	CleanDelete CleanMode = "delete"
+)
+
+// CleanOptions controls how CleanAllTables, CleanSpecificTables and CleanTables remove rows
+type CleanOptions struct {
+	Mode CleanMode // truncate or delete (defaults to truncate)
+
+	// RestartIdentity resets the sequences owned by the cleaned tables' SERIAL and IDENTITY
+	// columns, and standalone sequences, to their START value
+	RestartIdentity bool
+
+	// TrackDirtyTables installs triggers at startup that record which tables receive writes,
+	// so CleanAllTables only cleans tables written to since the last clean
+	TrackDirtyTables bool
+
+	// Schemas lists the schemas CleanAllTables cleans (defaults to public)
+	Schemas []string
+
+	// ExcludeTables holds globs of tables CleanAllTables leaves alone. A glob containing a dot
+	// is matched against "schema.table", any other against the table name alone.
+	ExcludeTables []string
+
+	// ExcludePatterns holds regular expressions of tables CleanAllTables leaves alone,
+	// matched against "schema.table"
+	ExcludePatterns []*regexp.Regexp
+
+	// PreserveTables lists tables no clean ever empties, such as lookup tables, in the same
+	// form as ExcludeTables. The migrations table and extension tables are always preserved.
+	// A clean that would cascade to a preserved table fails with ErrPreservedReference.
+	PreserveTables []string
+}
+
+// CleanOption overrides the container's CleanOptions for a single call
+type CleanOption func(*CleanOptions)
+
+// CleanWithMode selects the clean mode for a single call
+func CleanWithMode(mode CleanMode) CleanOption {
+	return func(o *CleanOptions) {
+		o.Mode = mode
+	}
+}
+
+// CleanWithRestartIdentity resets sequences to their START value for a single call
+func CleanWithRestartIdentity() CleanOption {
+	return func(o *CleanOptions) {
+		o.RestartIdentity = true
+	}
+}
+
+// CleanWithSchemas replaces the schemas CleanAllTables cleans for a single call
+func CleanWithSchemas(schemas ...string) CleanOption {
+	return func(o *CleanOptions) {
+		o.Schemas = schemas
+	}
+}
+
+// CleanExcluding adds table globs CleanAllTables leaves alone for a single call
+func CleanExcluding(globs ...string) CleanOption {
+	return func(o *CleanOptions) {
+		o.ExcludeTables = append(o.ExcludeTables, globs...)
+	}
+}
+
+// CleanExcludingPattern adds a regular expression of tables CleanAllTables leaves alone for a single call
+func CleanExcludingPattern(pattern *regexp.Regexp) CleanOption {
+	return func(o *CleanOptions) {
+		o.ExcludePatterns = append(o.ExcludePatterns, pattern)
+	}
+}
+
+// CleanPreserving adds tables no clean empties for a single call
+func CleanPreserving(tables ...string) CleanOption {
+	return func(o *CleanOptions) {
+		o.PreserveTables = append(o.PreserveTables, tables...)
+	}
+}
+
+// validateCleanOptions checks clean options
+func validateCleanOptions(options CleanOptions) error {
+	if err := validateCleanMode(options.Mode); err != nil {
+		return err
+	}
+	for _, schema := range options.Schemas {
+		if schema == "" {
+			return &ConfigError{Field: "Cleaning.Schemas", Value: schema, Reason: "must not contain an empty schema"}
+		}
+	}
+	if err := validateTableGlobs("Cleaning.ExcludeTables", options.ExcludeTables); err != nil {
+		return err
+	}
+	if err := validateTableGlobs("Cleaning.PreserveTables", options.PreserveTables); err != nil {
+		return err
+	}
+	for _, pattern := range options.ExcludePatterns {
+		if pattern == nil {
+			return &ConfigError{Field: "Cleaning.ExcludePatterns", Value: pattern, Reason: "must not contain nil"}
+		}
+	}
+	return nil
+}
+
+// validateTableGlobs checks that every glob is a valid, non-empty path.Match pattern
+func validateTableGlobs(field string, globs []string) error {
+	for _, glob := range globs {
+		if _, err := path.Match(glob, ""); glob == "" || err != nil {
+			return &ConfigError{Field: field, Value: glob, Reason: "is not a valid table name or glob"}
+		}
+	}
+	return nil
+}
+
+// validateCleanMode checks a clean mode
+func validateCleanMode(mode CleanMode) error {
+	switch mode {
+	case "", CleanTruncate, CleanDelete:
+		return nil
+	}
+	return &ConfigError{Field: "Cleaning.Mode", Value: string(mode), Reason: "is not a known clean mode"}
+}
+
+// cleanOptions returns the container's CleanOptions with opts applied
+func (tc *PostgreSQLTestContainer) cleanOptions(opts []CleanOption) (CleanOptions, error) {
+	var options CleanOptions
+	if tc.config != nil {
+		options = tc.config.Cleaning
+	}
+
+	// Per-call options append to the lists, which must not grow the container's
+	options.Schemas = slices.Clone(options.Schemas)
+	options.ExcludeTables = slices.Clone(options.ExcludeTables)
+	options.ExcludePatterns = slices.Clone(options.ExcludePatterns)
+	options.PreserveTables = append(tc.builtinPreservedTables(), options.PreserveTables...)
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if len(options.Schemas) == 0 {
+		options.Schemas = []string{"public"}
+	}
+
+	if err := validateCleanOptions(options); err != nil {
+		return options, err
+	}
+	return options, nil
+}
+
+// builtinPreservedTables returns the migrations table and the tables owned by the image's extensions
+func (tc *PostgreSQLTestContainer) builtinPreservedTables() []string {
+	return append([]string{tc.migrationsTable()}, tc.config.imageFamily().extensionTables()...)
+}
+
+// matchTable reports whether glob matches the table. A glob containing a dot is matched
+// against "schema.table", any other against the table name alone.
+func matchTable(glob, schema, table string) bool {
+	name := table
+	if strings.Contains(glob, ".") {
+		name = schema + "." + table
+	}
+	matched, _ := path.Match(glob, name)
+	return matched
+}
+
+// preserves reports whether the table must never be cleaned
+func (o CleanOptions) preserves(schema, table string) bool {
+	return slices.ContainsFunc(o.PreserveTables, func(glob string) bool {
+		return matchTable(glob, schema, table)
+	})
+}
+
+// excludes reports whether CleanAllTables leaves the table alone
+func (o CleanOptions) excludes(schema, table string) bool {
+	if o.preserves(schema, table) {
+		return true
+	}
+	for _, glob := range o.ExcludeTables {
+		if matchTable(glob, schema, table) {
+			return true
+		}
+	}
+	for _, pattern := range o.ExcludePatterns {
+		if pattern.MatchString(schema + "." + table) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanableTable is a table found in the catalog
+type cleanableTable struct {
+	name   string // Quoted, schema-qualified name
+	schema string
+	table  string
+	dirty  bool // Written to since the last clean; always set without tracking
+}
+
+// CleanAllTables removes all rows from all tables in the cleaned schemas for test isolation.
+// The migrations table, tables owned by extensions and excluded or preserved tables are kept.
+// WARNING: This removes ALL data from ALL other tables
+func (tc *PostgreSQLTestContainer) CleanAllTables(ctx context.Context, opts ...CleanOption) error {
+	options, err := tc.cleanOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	// Every table is listed, dirty or not, so that kept tables are known for RestartIdentity
+	dirty := "true"
+	if options.TrackDirtyTables {
+		dirty = `c.oid IN (SELECT relid FROM ` + trackingSchema + `.dirty_tables)`
+	}
+	rows, err := tc.Pool.Query(ctx, `
+		SELECT format('%I.%I', n.nspname, c.relname), n.nspname, c.relname, `+dirty+`
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p')
+		AND n.nspname = ANY($1)
+		AND n.nspname <> '`+trackingSchema+`'
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_depend dep
+			WHERE dep.classid = 'pg_class'::regclass AND dep.objid = c.oid AND dep.deptype = 'e'
+		)
+	`, options.Schemas)
+	if err != nil {
+		return fmt.Errorf("failed to get table names: %w", err)
+	}
+
+	found, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (cleanableTable, error) {
+		var t cleanableTable
+		err := row.Scan(&t.name, &t.schema, &t.table, &t.dirty)
+		return t, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan table names: %w", err)
+	}
+
+	var tables, kept []string
+	for _, t := range found {
+		switch {
+		case options.excludes(t.schema, t.table):
+			kept = append(kept, t.name)
+		case t.dirty:
+			tables = append(tables, t.name)
+		}
+	}
+
+	if err := tc.cleanTables(ctx, tables, kept, options, true); err != nil {
+		return fmt.Errorf("failed to clean tables: %w", err)
+	}
+
+	return nil
+}
+
+// CleanSpecificTables truncates specific tables for test isolation
+// Only truncates tables that actually exist to avoid errors
+func (tc *PostgreSQLTestContainer) CleanSpecificTables(ctx context.Context, tableNames ...string) error {
+	return tc.CleanTables(ctx, tableNames)
+}
+
+// CleanTables removes all rows from specific tables, skipping tables that do not exist and
+// preserved tables. Names are resolved like in SQL: they may be schema-qualified and quoted,
+// e.g. billing."Invoices"; unqualified names follow the search_path.
+// Tables referencing them are cleaned too, as with TRUNCATE ... CASCADE, unless preserved, in
+// which case the clean fails with ErrPreservedReference. With RestartIdentity,
+// the standalone sequences reset are those used by the column defaults of the given tables.
+func (tc *PostgreSQLTestContainer) CleanTables(ctx context.Context, tableNames []string, opts ...CleanOption) error {
+	options, err := tc.cleanOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	if len(tableNames) == 0 {
+		return nil
+	}
+
+	// Resolve the names, dropping those of tables that do not exist
+	rows, err := tc.Pool.Query(ctx, `
+		SELECT c.oid::regclass::text, n.nspname, c.relname
+		FROM unnest($1::text[]) WITH ORDINALITY AS t(name, position)
+		JOIN pg_class c ON c.oid = to_regclass(t.name)
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		ORDER BY t.position
+	`, tableNames)
+	if err != nil {
+		return fmt.Errorf("failed to resolve tables %v: %w", tableNames, err)
+	}
+
+	found, err := pgx.CollectRows(rows, scanCleanableTable)
+	if err != nil {
+		return fmt.Errorf("failed to scan tables: %w", err)
+	}
+
+	var existingTables []string
+	for _, t := range found {
+		if !options.preserves(t.schema, t.table) {
+			existingTables = append(existingTables, t.name)
+		}
+	}
+
+	if err := tc.cleanTables(ctx, existingTables, nil, options, false); err != nil {
+		return fmt.Errorf("failed to clean specific tables: %w", err)
+	}
+
+	return nil
+}
+
+// scanCleanableTable scans a row of name, schema and table
+func scanCleanableTable(row pgx.CollectableRow) (cleanableTable, error) {
+	var t cleanableTable
+	err := row.Scan(&t.name, &t.schema, &t.table)
+	return t, err
+}
+
+// cleanTables removes all rows from tables using the configured mode. all is set when tables
+// were chosen from every table in the database: with RestartIdentity every standalone sequence
+// not used by the kept tables is reset, and with dirty table tracking every mark is cleared.
+func (tc *PostgreSQLTestContainer) cleanTables(ctx context.Context, tables, kept []string, options CleanOptions, all bool) error {
+	// Tracking may leave no table to clean, but sequences can be advanced without writing to one
+	if len(tables) == 0 && !(all && options.RestartIdentity) {
+		return nil
+	}
+
+	return pgx.BeginFunc(ctx, tc.Pool, func(tx pgx.Tx) error {
+		if len(tables) > 0 {
+			if err := cleanRows(ctx, tx, tables, options); err != nil {
+				return err
+			}
+		}
+
+		if options.RestartIdentity {
+			if err := restartStandaloneSequences(ctx, tx, tables, kept, options.Schemas, all); err != nil {
+				return err
+			}
+		}
+
+		// Cleaning is itself a write, so the marks are cleared afterwards
+		if options.TrackDirtyTables {
+			return clearDirtyMarks(ctx, tx, tables, all)
+		}
+		return nil
+	})
+}
+
+// cleanRows removes every row of tables, and of the tables referencing them. A preserved table
+// referencing them cannot be left intact, so the clean fails with ErrPreservedReference.
+func cleanRows(ctx context.Context, tx pgx.Tx, tables []string, options CleanOptions) error {
+	// Normalise the names so that they match the foreign key graph
+	var normalised []string
+	if err := tx.QueryRow(ctx, "SELECT array_agg(t::regclass::text) FROM unnest($1::text[]) AS t", tables).Scan(&normalised); err != nil {
+		return fmt.Errorf("failed to resolve tables: %w", err)
+	}
+
+	fks, err := loadForeignKeys(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	closure, conflicts := cleanClosure(normalised, fks, options.preserves)
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w: %s; preserve the referenced tables too, or stop preserving the referencing ones",
+			ErrPreservedReference, strings.Join(conflicts, ", "))
+	}
+
+	if options.Mode == CleanDelete {
+		return deleteTables(ctx, tx, closure, fks, options.RestartIdentity)
+	}
+
+	// CASCADE only reaches tables already in the closure, since no preserved table references it
+	truncateSQL := "TRUNCATE " + strings.Join(closure, ", ")
+	if options.RestartIdentity {
+		truncateSQL += " RESTART IDENTITY"
+	}
+	_, err = tx.Exec(ctx, truncateSQL+" CASCADE")
+	return err
+}
+
+// ownedSequencesSQL resets the sequences owned by SERIAL and IDENTITY columns of the tables in $1
+const ownedSequencesSQL = `
+	SELECT setval(s.seqrelid::regclass, s.seqstart, false)
+	FROM pg_sequence s
+	JOIN pg_depend d ON d.classid = 'pg_class'::regclass AND d.objid = s.seqrelid
+	WHERE d.refclassid = 'pg_class'::regclass
+	AND d.deptype IN ('a', 'i')
+	AND d.refobjid IN (SELECT t::regclass FROM unnest($1::text[]) AS t)
+`
+
+// standaloneSequencesSQL resets sequences not owned by any column. %s restricts the sequences.
+const standaloneSequencesSQL = `
+	SELECT setval(s.seqrelid::regclass, s.seqstart, false)
+	FROM pg_sequence s
+	JOIN pg_class c ON c.oid = s.seqrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE NOT EXISTS (
+		SELECT 1 FROM pg_depend d
+		WHERE d.classid = 'pg_class'::regclass AND d.objid = s.seqrelid AND d.deptype IN ('a', 'i', 'e')
+	)
+	AND %s
+`
+
+// defaultSequencesSQL selects the sequences used by the column defaults of the tables in %s
+const defaultSequencesSQL = `
+	SELECT d.refobjid
+	FROM pg_depend d
+	JOIN pg_attrdef ad ON ad.oid = d.objid
+	WHERE d.classid = 'pg_attrdef'::regclass
+	AND d.refclassid = 'pg_class'::regclass
+	AND ad.adrelid IN (SELECT t::regclass FROM unnest(%s::text[]) AS t)
+`
+
+// restartStandaloneSequences resets sequences not owned by any column to their START value.
+// With all set it resets every such sequence in schemas except those used by the column
+// defaults of kept tables, which still hold rows; otherwise only those used by the column
+// defaults of tables.
+func restartStandaloneSequences(ctx context.Context, tx pgx.Tx, tables, kept, schemas []string, all bool) error {
+	var err error
+	if all {
+		restrict := "n.nspname = ANY($1) AND s.seqrelid NOT IN (" + fmt.Sprintf(defaultSequencesSQL, "$2") + ")"
+		_, err = tx.Exec(ctx, fmt.Sprintf(standaloneSequencesSQL, restrict), schemas, kept)
+	} else {
+		restrict := "s.seqrelid IN (" + fmt.Sprintf(defaultSequencesSQL, "$1") + ")"
+		_, err = tx.Exec(ctx, fmt.Sprintf(standaloneSequencesSQL, restrict), tables)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restart sequences: %w", err)
+	}
+	return nil
+}
+
+// foreignKey is a foreign key constraint from table to references
+type foreignKey struct {
+	name        string
+	schema      string // Schema of the constraint, as regnamespace text
+	table       string // Referencing table, as regclass text
+	tableSchema string // Unquoted schema and name of the referencing table, for PreserveTables
+	tableName   string
+	references  string // Referenced table, as regclass text
+	deferrable  bool
+	onDelete    string // confdeltype: a (NO ACTION), r (RESTRICT), c (CASCADE), n or d (SET NULL/DEFAULT)
+}
+
+// blocksDelete reports whether the constraint fails a DELETE that leaves referencing rows behind.
+// A self-reference without RESTRICT is only checked once the whole statement has run.
+func (fk foreignKey) blocksDelete() bool {
+	if fk.table == fk.references {
+		return fk.onDelete == "r"
+	}
+	return fk.onDelete == "a" || fk.onDelete == "r"
+}
+
+// canDefer reports whether the check can wait until the end of the transaction. RESTRICT is
+// checked immediately even on a DEFERRABLE constraint.
+func (fk foreignKey) canDefer() bool {
+	return fk.deferrable && fk.onDelete != "r"
+}
+
+// qualifiedName returns the constraint name as SET CONSTRAINTS takes it
+func (fk foreignKey) qualifiedName() string {
+	return fk.schema + "." + pgx.Identifier{fk.name}.Sanitize()
+}
+
+// loadForeignKeys reads every foreign key constraint in the database
+func loadForeignKeys(ctx context.Context, tx pgx.Tx) ([]foreignKey, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT con.conname, con.connamespace::regnamespace::text, con.conrelid::regclass::text,
+			n.nspname, c.relname, con.confrelid::regclass::text, con.condeferrable, con.confdeltype::text
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE con.contype = 'f'
+		ORDER BY con.conrelid::regclass::text, con.conname
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read foreign keys: %w", err)
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (foreignKey, error) {
+		var fk foreignKey
+		err := row.Scan(&fk.name, &fk.schema, &fk.table, &fk.tableSchema, &fk.tableName, &fk.references, &fk.deferrable, &fk.onDelete)
+		return fk, err
+	})
+}
+
+// deleteTables deletes every row of closure, which holds every table referencing its tables, in
+// foreign key order. Within a reference cycle, the keys the order cannot satisfy are deferred
+// until all rows are gone, so they must be declared DEFERRABLE; the schema is never altered. With
+// restartIdentity, the sequences owned by the deleted tables are reset as TRUNCATE would.
+func deleteTables(ctx context.Context, tx pgx.Tx, closure []string, fks []foreignKey, restartIdentity bool) error {
+	plan := planDelete(closure, fks)
+
+	if len(plan.blocking) > 0 {
+		return fmt.Errorf("%w: no delete order satisfies %s; declare one of them DEFERRABLE without ON DELETE RESTRICT, or use truncate mode",
+			ErrNotDeferrable, strings.Join(constraintNames(plan.blocking), ", "))
+	}
+
+	deferred := strings.Join(constraintQualifiedNames(plan.deferred), ", ")
+	if deferred != "" {
+		if _, err := tx.Exec(ctx, "SET CONSTRAINTS "+deferred+" DEFERRED"); err != nil {
+			return fmt.Errorf("failed to defer constraints: %w", err)
+		}
+	}
+
+	for _, table := range plan.order {
+		if _, err := tx.Exec(ctx, "DELETE FROM "+table); err != nil {
+			return fmt.Errorf("failed to delete from %s: %w", table, err)
+		}
+	}
+
+	if restartIdentity {
+		if _, err := tx.Exec(ctx, ownedSequencesSQL, closure); err != nil {
+			return fmt.Errorf("failed to restart identities: %w", err)
+		}
+	}
+
+	// Check the deferred constraints now, so that later statements in the transaction see them checked
+	if deferred != "" {
+		if _, err := tx.Exec(ctx, "SET CONSTRAINTS "+deferred+" IMMEDIATE"); err != nil {
+			return fmt.Errorf("failed to check constraints: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// constraintNames returns the constraints as table.constraint, for error messages
+func constraintNames(fks []foreignKey) []string {
+	names := make([]string, 0, len(fks))
+	for _, fk := range fks {
+		names = append(names, fk.table+"."+pgx.Identifier{fk.name}.Sanitize())
+	}
+	return names
+}
+
+// constraintQualifiedNames returns the distinct schema-qualified names of the constraints
+func constraintQualifiedNames(fks []foreignKey) []string {
+	var names []string
+	for _, fk := range fks {
+		if name := fk.qualifiedName(); !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// cleanClosure returns tables plus every table that references them, directly or not, leaving out
+// tables preserved reports. It also returns the foreign keys, as table.constraint, by which a
+// preserved table references the closure: cleaning would empty or break that table.
+func cleanClosure(tables []string, fks []foreignKey, preserved func(schema, table string) bool) ([]string, []string) {
+	var kept []foreignKey
+	for _, fk := range fks {
+		if !preserved(fk.tableSchema, fk.tableName) {
+			kept = append(kept, fk)
+		}
+	}
+	closure := referencingClosure(tables, kept)
+
+	var conflicts []foreignKey
+	for _, fk := range fks {
+		if preserved(fk.tableSchema, fk.tableName) && slices.Contains(closure, fk.references) && !slices.Contains(closure, fk.table) {
+			conflicts = append(conflicts, fk)
+		}
+	}
+	return closure, constraintNames(conflicts)
+}
+
+// referencingClosure returns tables plus every table that references them, directly or not
+func referencingClosure(tables []string, fks []foreignKey) []string {
+	seen := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		seen[table] = true
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, fk := range fks {
+			if seen[fk.references] && !seen[fk.table] {
+				seen[fk.table] = true
+				changed = true
+			}
+		}
+	}
+
+	return sortedKeys(seen)
+}
+
+// deletePlan is the order in which delete mode empties tables
+type deletePlan struct {
+	order    []string     // Every table comes before the tables it references, except along deferred keys
+	deferred []foreignKey // Keys within a cycle whose referenced table is emptied first
+	blocking []foreignKey // Keys within a cycle that no order satisfies and that cannot be deferred
+}
+
+// planDelete sorts tables so that every table comes before the tables it references. Tables of a
+// reference cycle (a strongly connected component of the foreign key graph) are ordered by the
+// keys that cannot be deferred; the keys of the cycle that order still breaks are deferred.
+func planDelete(tables []string, fks []foreignKey) deletePlan {
+	included := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		included[table] = true
+	}
+
+	// Edges run from a referenced table to the tables referencing it, so Tarjan's algorithm
+	// emits referencing tables before the tables they reference. Self-references are their own
+	// component already.
+	referencedBy := make(map[string][]string)
+	for _, fk := range fks {
+		if !included[fk.table] || !included[fk.references] || fk.table == fk.references {
+			continue
+		}
+		referencedBy[fk.references] = append(referencedBy[fk.references], fk.table)
+	}
+
+	var (
+		components [][]string
+		component  = make(map[string]int)
+		stack      []string
+		onStack    = make(map[string]bool)
+		index      = make(map[string]int)
+		lowlink    = make(map[string]int)
+		visit      func(table string)
+	)
+	visit = func(table string) {
+		index[table] = len(index)
+		lowlink[table] = index[table]
+		stack = append(stack, table)
+		onStack[table] = true
+
+		for _, next := range referencedBy[table] {
+			if _, visited := index[next]; !visited {
+				visit(next)
+				lowlink[table] = min(lowlink[table], lowlink[next])
+			} else if onStack[next] {
+				lowlink[table] = min(lowlink[table], index[next])
+			}
+		}
+
+		if lowlink[table] != index[table] {
+			return
+		}
+
+		// table is the root of a strongly connected component
+		var members []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			members = append(members, top)
+			if top == table {
+				break
+			}
+		}
+		for _, member := range members {
+			component[member] = len(components)
+		}
+		components = append(components, members)
+	}
+
+	for _, table := range slices.Sorted(slices.Values(tables)) {
+		if _, visited := index[table]; !visited {
+			visit(table)
+		}
+	}
+
+	// Group the keys linking two tables of the same component
+	internal := make([][]foreignKey, len(components))
+	for _, fk := range fks {
+		from, okFrom := component[fk.table]
+		to, okTo := component[fk.references]
+		if okFrom && okTo && from == to && fk.blocksDelete() {
+			internal[from] = append(internal[from], fk)
+		}
+	}
+
+	var plan deletePlan
+	for id, members := range components {
+		order, blocking := orderCycle(members, internal[id])
+		plan.order = append(plan.order, order...)
+		plan.blocking = append(plan.blocking, blocking...)
+
+		position := make(map[string]int, len(order))
+		for i, table := range order {
+			position[table] = i
+		}
+		for _, fk := range internal[id] {
+			if fk.canDefer() && position[fk.table] > position[fk.references] {
+				plan.deferred = append(plan.deferred, fk)
+			}
+		}
+	}
+
+	return plan
+}
+
+// orderCycle sorts the tables of a component so that every table comes before the tables it
+// references through a key that cannot be deferred, breaking ties by name. It returns the keys
+// that cannot be deferred and form a cycle of their own, or reference their own table.
+func orderCycle(members []string, fks []foreignKey) ([]string, []foreignKey) {
+	var pinned, blocking []foreignKey
+	for _, fk := range fks {
+		switch {
+		case fk.canDefer():
+		case fk.table == fk.references:
+			blocking = append(blocking, fk)
+		default:
+			pinned = append(pinned, fk)
+		}
+	}
+
+	// Kahn's algorithm: a table is ready once every table referencing it through a pinned key
+	// has been placed
+	remaining := slices.Sorted(slices.Values(members))
+	var order []string
+	for len(remaining) > 0 {
+		next := slices.IndexFunc(remaining, func(table string) bool {
+			return !slices.ContainsFunc(pinned, func(fk foreignKey) bool {
+				return fk.references == table && fk.table != table && slices.Contains(remaining, fk.table)
+			})
+		})
+		if next < 0 {
+			break
+		}
+		order = append(order, remaining[next])
+		remaining = slices.Delete(remaining, next, next+1)
+	}
+
+	// The pinned keys between the tables left form a cycle no order satisfies
+	for _, fk := range pinned {
+		if slices.Contains(remaining, fk.table) && slices.Contains(remaining, fk.references) {
+			blocking = append(blocking, fk)
+		}
+	}
+	return append(order, remaining...), blocking
+}
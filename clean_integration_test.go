@@ -0,0 +1,335 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// createCleanSchema creates tables with a foreign key chain, a self-reference and a cycle with a
+// single DEFERRABLE key
+func createCleanSchema(t *testing.T, tc *PostgreSQLTestContainer) {
+	t.Helper()
+
+	_, err := tc.Pool.Exec(context.Background(), `
+		CREATE TABLE users (id INT PRIMARY KEY);
+		CREATE TABLE posts (id INT PRIMARY KEY, user_id INT NOT NULL REFERENCES users ON DELETE RESTRICT);
+		CREATE TABLE comments (id INT PRIMARY KEY, post_id INT NOT NULL REFERENCES posts);
+		CREATE TABLE categories (id INT PRIMARY KEY, parent_id INT REFERENCES categories);
+		CREATE TABLE departments (id INT PRIMARY KEY, manager_id INT);
+		CREATE TABLE employees (id INT PRIMARY KEY, department_id INT NOT NULL REFERENCES departments);
+		ALTER TABLE departments ADD CONSTRAINT departments_manager_fk FOREIGN KEY (manager_id) REFERENCES employees DEFERRABLE;
+
+		INSERT INTO users VALUES (1);
+		INSERT INTO posts VALUES (1, 1);
+		INSERT INTO comments VALUES (1, 1);
+		INSERT INTO categories VALUES (1, NULL), (2, 1);
+		INSERT INTO departments VALUES (1, NULL);
+		INSERT INTO employees VALUES (1, 1);
+		UPDATE departments SET manager_id = 1;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+}
+
+func countRows(t *testing.T, tc *PostgreSQLTestContainer, table string) int {
+	t.Helper()
+
+	var count int
+	if err := tc.Pool.QueryRow(context.Background(), "SELECT count(*) FROM "+table).Scan(&count); err != nil {
+		t.Fatalf("Failed to count %s: %v", table, err)
+	}
+	return count
+}
+
+func TestCleanAllTables_DeleteMode(t *testing.T) {
+	ctx := context.Background()
+	tc := New(t, WithImageFamily(ImageFamilyPostgres, ""), WithCleaning(CleanOptions{Mode: CleanDelete}))
+	createCleanSchema(t, tc)
+
+	if err := tc.CleanAllTables(ctx); err != nil {
+		t.Fatalf("Failed to clean all tables: %v", err)
+	}
+
+	for _, table := range []string{"users", "posts", "comments", "categories", "departments", "employees"} {
+		if count := countRows(t, tc, table); count != 0 {
+			t.Errorf("Expected %s to be empty, got %d rows", table, count)
+		}
+	}
+
+}
+
+func TestCleanAllTables_DeleteModeNotDeferrable(t *testing.T) {
+	ctx := context.Background()
+	tc := New(t, WithImageFamily(ImageFamilyPostgres, ""), WithCleaning(CleanOptions{Mode: CleanDelete}))
+	createCleanSchema(t, tc)
+
+	if _, err := tc.Pool.Exec(ctx, "ALTER TABLE departments ALTER CONSTRAINT departments_manager_fk NOT DEFERRABLE"); err != nil {
+		t.Fatalf("Failed to alter constraint: %v", err)
+	}
+
+	err := tc.CleanAllTables(ctx)
+	if !errors.Is(err, ErrNotDeferrable) {
+		t.Fatalf("Expected ErrNotDeferrable, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "departments_manager_fk") {
+		t.Errorf("Expected the error to name the constraint, got %v", err)
+	}
+
+	// The schema is left alone and nothing is deleted
+	var deferrable bool
+	err = tc.Pool.QueryRow(ctx, "SELECT condeferrable FROM pg_constraint WHERE conname = 'departments_manager_fk'").Scan(&deferrable)
+	if err != nil {
+		t.Fatalf("Failed to read constraint: %v", err)
+	}
+	if deferrable {
+		t.Error("Expected departments_manager_fk to stay NOT DEFERRABLE")
+	}
+	if count := countRows(t, tc, "users"); count != 1 {
+		t.Errorf("Expected users to keep its row, got %d rows", count)
+	}
+}
+
+func TestCleanTables_DeleteMode(t *testing.T) {
+	ctx := context.Background()
+	tc := New(t, WithImageFamily(ImageFamilyPostgres, ""))
+	createCleanSchema(t, tc)
+
+	if err := tc.CleanTables(ctx, []string{"posts", "missing"}, CleanWithMode(CleanDelete)); err != nil {
+		t.Fatalf("Failed to clean tables: %v", err)
+	}
+
+	// Tables referencing posts are cleaned too, as with TRUNCATE ... CASCADE
+	for table, want := range map[string]int{"posts": 0, "comments": 0, "users": 1, "categories": 2} {
+		if count := countRows(t, tc, table); count != want {
+			t.Errorf("Expected %d rows in %s, got %d", want, table, count)
+		}
+	}
+}
+
+func TestCleanAllTables_RestartIdentity(t *testing.T) {
+	for _, mode := range []CleanMode{CleanTruncate, CleanDelete} {
+		t.Run(string(mode), func(t *testing.T) {
+			ctx := context.Background()
+			tc := New(t, WithImageFamily(ImageFamilyPostgres, ""))
+
+			_, err := tc.Pool.Exec(ctx, `
+				CREATE SEQUENCE invoice_numbers START 1000;
+				CREATE SEQUENCE unused_numbers START 50;
+				CREATE TABLE serials (id SERIAL PRIMARY KEY);
+				CREATE TABLE identities (id INT GENERATED ALWAYS AS IDENTITY (START 10) PRIMARY KEY);
+				CREATE TABLE invoices (number INT DEFAULT nextval('invoice_numbers'));
+				INSERT INTO serials DEFAULT VALUES;
+				INSERT INTO identities DEFAULT VALUES;
+				INSERT INTO invoices DEFAULT VALUES;
+				SELECT nextval('unused_numbers');
+			`)
+			if err != nil {
+				t.Fatalf("Failed to create tables: %v", err)
+			}
+
+			if err := tc.CleanAllTables(ctx, CleanWithMode(mode), CleanWithRestartIdentity()); err != nil {
+				t.Fatalf("Failed to clean all tables: %v", err)
+			}
+
+			for query, want := range map[string]int{
+				"INSERT INTO serials DEFAULT VALUES RETURNING id":      1,
+				"INSERT INTO identities DEFAULT VALUES RETURNING id":   10,
+				"INSERT INTO invoices DEFAULT VALUES RETURNING number": 1000,
+				"SELECT nextval('unused_numbers')::int":                50,
+			} {
+				var got int
+				if err := tc.Pool.QueryRow(ctx, query).Scan(&got); err != nil {
+					t.Fatalf("Failed to run %q: %v", query, err)
+				}
+				if got != want {
+					t.Errorf("Expected %q to return %d, got %d", query, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCleanAllTables_RestartIdentityKeepsPreservedSequences(t *testing.T) {
+	ctx := context.Background()
+	tc := New(t, WithImageFamily(ImageFamilyPostgres, ""))
+
+	_, err := tc.Pool.Exec(ctx, `
+		CREATE SEQUENCE country_ids;
+		CREATE SEQUENCE currency_ids;
+		CREATE SEQUENCE order_ids;
+		CREATE TABLE countries (id INT PRIMARY KEY DEFAULT nextval('country_ids'), name TEXT);
+		CREATE TABLE currencies (id INT PRIMARY KEY DEFAULT nextval('currency_ids'), code TEXT);
+		CREATE TABLE orders (id INT PRIMARY KEY DEFAULT nextval('order_ids'));
+		INSERT INTO countries (name) VALUES ('France'), ('Japan');
+		INSERT INTO currencies (code) VALUES ('EUR'), ('JPY');
+		INSERT INTO orders DEFAULT VALUES;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	err = tc.CleanAllTables(ctx, CleanWithRestartIdentity(), CleanPreserving("countries"), CleanExcluding("currencies"))
+	if err != nil {
+		t.Fatalf("Failed to clean all tables: %v", err)
+	}
+
+	// Inserting into the kept tables must not collide with their existing rows
+	for _, query := range []string{
+		"INSERT INTO countries (name) VALUES ('Peru')",
+		"INSERT INTO currencies (code) VALUES ('PEN')",
+	} {
+		if _, err := tc.Pool.Exec(ctx, query); err != nil {
+			t.Errorf("Failed to run %q: %v", query, err)
+		}
+	}
+
+	var orderID int
+	if err := tc.Pool.QueryRow(ctx, "INSERT INTO orders DEFAULT VALUES RETURNING id").Scan(&orderID); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if orderID != 1 {
+		t.Errorf("Expected the sequence of a cleaned table to restart at 1, got %d", orderID)
+	}
+}
+
+func TestCleanTables_RestartIdentity(t *testing.T) {
+	ctx := context.Background()
+	tc := New(t, WithImageFamily(ImageFamilyPostgres, ""), WithCleaning(CleanOptions{RestartIdentity: true}))
+
+	_, err := tc.Pool.Exec(ctx, `
+		CREATE SEQUENCE shared_numbers;
+		CREATE TABLE cleaned (id SERIAL PRIMARY KEY, number INT DEFAULT nextval('shared_numbers'));
+		CREATE TABLE kept (id SERIAL PRIMARY KEY);
+		INSERT INTO cleaned DEFAULT VALUES;
+		INSERT INTO kept DEFAULT VALUES;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	if err := tc.CleanSpecificTables(ctx, "cleaned"); err != nil {
+		t.Fatalf("Failed to clean tables: %v", err)
+	}
+
+	var id, number, keptID int
+	if err := tc.Pool.QueryRow(ctx, "INSERT INTO cleaned DEFAULT VALUES RETURNING id, number").Scan(&id, &number); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if id != 1 || number != 1 {
+		t.Errorf("Expected id and number to restart at 1, got %d and %d", id, number)
+	}
+	if err := tc.Pool.QueryRow(ctx, "INSERT INTO kept DEFAULT VALUES RETURNING id").Scan(&keptID); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if keptID != 2 {
+		t.Errorf("Expected the sequence of an uncleaned table to keep counting, got %d", keptID)
+	}
+}
+
+func TestCleanAllTables_Schemas(t *testing.T) {
+	ctx := context.Background()
+	tc := New(t, WithImageFamily(ImageFamilyPostgres, ""), WithCleaning(CleanOptions{
+		Schemas:        []string{"public", "billing", "audit"},
+		ExcludeTables:  []string{"audit.*"},
+		PreserveTables: []string{"billing.currencies"},
+	}))
+
+	_, err := tc.Pool.Exec(ctx, `
+		CREATE SCHEMA billing;
+		CREATE SCHEMA audit;
+		CREATE SCHEMA outbox;
+		CREATE TABLE billing.currencies (code TEXT PRIMARY KEY);
+		CREATE TABLE billing."Invoices" (id SERIAL PRIMARY KEY, currency TEXT REFERENCES billing.currencies (code));
+		CREATE TABLE audit.events (id SERIAL PRIMARY KEY);
+		CREATE TABLE outbox.messages (id SERIAL PRIMARY KEY);
+		CREATE TABLE notes (id SERIAL PRIMARY KEY);
+		INSERT INTO billing.currencies VALUES ('EUR'), ('GBP');
+		INSERT INTO billing."Invoices" (currency) VALUES ('EUR');
+		INSERT INTO audit.events DEFAULT VALUES;
+		INSERT INTO outbox.messages DEFAULT VALUES;
+		INSERT INTO notes DEFAULT VALUES;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create schemas: %v", err)
+	}
+
+	if err := tc.CleanAllTables(ctx); err != nil {
+		t.Fatalf("Failed to clean all tables: %v", err)
+	}
+
+	for table, want := range map[string]int{
+		`billing."Invoices"`: 0,
+		"notes":              0,
+		"billing.currencies": 2, // Preserved
+		"audit.events":       1, // Excluded
+		"outbox.messages":    1, // Not a cleaned schema
+	} {
+		if count := countRows(t, tc, table); count != want {
+			t.Errorf("Expected %d rows in %s, got %d", want, table, count)
+		}
+	}
+
+	// Named tables are resolved like SQL, and preserved tables are skipped
+	if err := tc.CleanSpecificTables(ctx, `audit.events`, `billing.currencies`, `outbox."missing"`); err != nil {
+		t.Fatalf("Failed to clean specific tables: %v", err)
+	}
+	if count := countRows(t, tc, "audit.events"); count != 0 {
+		t.Errorf("Expected audit.events to be empty, got %d rows", count)
+	}
+	if count := countRows(t, tc, "billing.currencies"); count != 2 {
+		t.Errorf("Expected billing.currencies to be preserved, got %d rows", count)
+	}
+
+	// Per-call schemas replace the container's
+	if err := tc.CleanAllTables(ctx, CleanWithSchemas("outbox")); err != nil {
+		t.Fatalf("Failed to clean the outbox schema: %v", err)
+	}
+	if count := countRows(t, tc, "outbox.messages"); count != 0 {
+		t.Errorf("Expected outbox.messages to be empty, got %d rows", count)
+	}
+}
+
+func TestCleanAllTables_PreservedTableReferencesCleanedTable(t *testing.T) {
+	for _, mode := range []CleanMode{CleanTruncate, CleanDelete} {
+		t.Run(string(mode), func(t *testing.T) {
+			ctx := context.Background()
+			tc := New(t, WithImageFamily(ImageFamilyPostgres, ""), WithCleaning(CleanOptions{
+				Mode:           mode,
+				PreserveTables: []string{"countries"},
+			}))
+
+			_, err := tc.Pool.Exec(ctx, `
+				CREATE TABLE regions (id INT PRIMARY KEY);
+				CREATE TABLE countries (id INT PRIMARY KEY, region_id INT REFERENCES regions ON DELETE CASCADE);
+				INSERT INTO regions VALUES (1);
+				INSERT INTO countries VALUES (1, 1);
+			`)
+			if err != nil {
+				t.Fatalf("Failed to create tables: %v", err)
+			}
+
+			err = tc.CleanAllTables(ctx)
+			if !errors.Is(err, ErrPreservedReference) {
+				t.Fatalf("Expected ErrPreservedReference, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "countries_region_id_fkey") {
+				t.Errorf("Expected the error to name the foreign key, got %v", err)
+			}
+			for _, table := range []string{"regions", "countries"} {
+				if count := countRows(t, tc, table); count != 1 {
+					t.Errorf("Expected %s to keep its row, got %d rows", table, count)
+				}
+			}
+
+			// Preserving the referenced table as well lets the clean run
+			if err := tc.CleanAllTables(ctx, CleanPreserving("regions")); err != nil {
+				t.Fatalf("Failed to clean all tables: %v", err)
+			}
+		})
+	}
+}
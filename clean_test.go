@@ -0,0 +1,407 @@
+package postgres
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestPlanDelete(t *testing.T) {
+	tests := []struct {
+		name         string
+		tables       []string
+		fks          []foreignKey
+		wantOrder    []string
+		wantDeferred []string
+	}{
+		{
+			name:      "no foreign keys",
+			tables:    []string{"b", "a"},
+			wantOrder: []string{"a", "b"},
+		},
+		{
+			name:   "chain",
+			tables: []string{"users", "posts", "comments"},
+			fks: []foreignKey{
+				{name: "posts_user_fk", table: "posts", references: "users", onDelete: "a"},
+				{name: "comments_post_fk", table: "comments", references: "posts", onDelete: "a"},
+			},
+			wantOrder: []string{"comments", "posts", "users"},
+		},
+		{
+			name:   "diamond",
+			tables: []string{"a", "b", "c", "d"},
+			fks: []foreignKey{
+				{name: "b_a", table: "b", references: "a", onDelete: "a"},
+				{name: "c_a", table: "c", references: "a", onDelete: "a"},
+				{name: "d_b", table: "d", references: "b", onDelete: "a"},
+				{name: "d_c", table: "d", references: "c", onDelete: "a"},
+			},
+			wantOrder: []string{"d", "b", "c", "a"},
+		},
+		{
+			name:   "self reference",
+			tables: []string{"categories"},
+			fks: []foreignKey{
+				{name: "categories_parent_fk", table: "categories", references: "categories", onDelete: "a"},
+			},
+			wantOrder: []string{"categories"},
+		},
+		{
+			name:   "cycle ordered by its non-deferrable key",
+			tables: []string{"audit", "departments", "employees"},
+			fks: []foreignKey{
+				{name: "departments_manager_fk", table: "departments", references: "employees", onDelete: "a", deferrable: true},
+				{name: "employees_department_fk", table: "employees", references: "departments", onDelete: "a"},
+				{name: "audit_employee_fk", table: "audit", references: "employees", onDelete: "r"},
+			},
+			wantOrder:    []string{"audit", "employees", "departments"},
+			wantDeferred: []string{"departments_manager_fk"},
+		},
+		{
+			name:   "cycle with every key deferrable",
+			tables: []string{"departments", "employees"},
+			fks: []foreignKey{
+				{name: "departments_manager_fk", table: "departments", references: "employees", onDelete: "a", deferrable: true},
+				{name: "employees_department_fk", table: "employees", references: "departments", onDelete: "a", deferrable: true},
+			},
+			wantOrder:    []string{"departments", "employees"},
+			wantDeferred: []string{"employees_department_fk"},
+		},
+		{
+			name:   "cycle broken by cascade",
+			tables: []string{"members", "teams"},
+			fks: []foreignKey{
+				{name: "teams_lead_fk", table: "teams", references: "members", onDelete: "n"},
+				{name: "members_team_fk", table: "members", references: "teams", onDelete: "c"},
+			},
+			wantOrder: []string{"members", "teams"},
+		},
+		{
+			name:   "deferrable restrict key is not deferred",
+			tables: []string{"a", "b"},
+			fks: []foreignKey{
+				{name: "a_b", table: "a", references: "b", onDelete: "r", deferrable: true},
+				{name: "b_a", table: "b", references: "a", onDelete: "a", deferrable: true},
+			},
+			wantOrder:    []string{"a", "b"},
+			wantDeferred: []string{"b_a"},
+		},
+		{
+			name:   "foreign key outside the tables",
+			tables: []string{"posts"},
+			fks: []foreignKey{
+				{name: "posts_user_fk", table: "posts", references: "users", onDelete: "a"},
+			},
+			wantOrder: []string{"posts"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := planDelete(tt.tables, tt.fks)
+			if !slices.Equal(plan.order, tt.wantOrder) {
+				t.Errorf("Expected order %v, got %v", tt.wantOrder, plan.order)
+			}
+			if got := keyNames(plan.deferred); !slices.Equal(got, tt.wantDeferred) {
+				t.Errorf("Expected deferred keys %v, got %v", tt.wantDeferred, got)
+			}
+			if len(plan.blocking) > 0 {
+				t.Errorf("Expected no blocking keys, got %v", keyNames(plan.blocking))
+			}
+
+			// Every table must come before the tables it references, except along deferred keys
+			position := make(map[string]int)
+			for i, table := range plan.order {
+				position[table] = i
+			}
+			for _, fk := range tt.fks {
+				child, okChild := position[fk.table]
+				parent, okParent := position[fk.references]
+				if okChild && okParent && fk.blocksDelete() && child > parent && !slices.Contains(plan.deferred, fk) {
+					t.Errorf("Expected %s to be deleted before %s, got %v", fk.table, fk.references, plan.order)
+				}
+			}
+		})
+	}
+}
+
+// keyNames returns the names of the constraints
+func keyNames(fks []foreignKey) []string {
+	var names []string
+	for _, fk := range fks {
+		names = append(names, fk.name)
+	}
+	return names
+}
+
+func TestPlanDelete_Blocking(t *testing.T) {
+	fks := []foreignKey{
+		// departments <-> employees is a cycle without any key that can be deferred
+		{name: "departments_manager_fk", table: "departments", references: "employees", onDelete: "a"},
+		{name: "employees_department_fk", table: "employees", references: "departments", onDelete: "a"},
+		// audit references the cycle from outside it, so the delete order satisfies it
+		{name: "audit_employee_fk", table: "audit", references: "employees", onDelete: "r"},
+		// Self-references are checked at the end of the statement unless they RESTRICT, which
+		// cannot be deferred
+		{name: "categories_parent_fk", table: "categories", references: "categories", onDelete: "a"},
+		{name: "folders_parent_fk", table: "folders", references: "folders", onDelete: "r", deferrable: true},
+	}
+
+	plan := planDelete([]string{"audit", "categories", "departments", "employees", "folders"}, fks)
+	got := constraintNames(plan.blocking)
+	want := []string{`departments."departments_manager_fk"`, `employees."employees_department_fk"`, `folders."folders_parent_fk"`}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected blocking constraints %v, got %v", want, got)
+	}
+}
+
+func TestPlanDelete_SeparateCycles(t *testing.T) {
+	// Both tables are in a cycle, but not the same one
+	fks := []foreignKey{
+		{name: "a_b", table: "a", references: "b", onDelete: "a", deferrable: true},
+		{name: "b_a", table: "b", references: "a", onDelete: "a", deferrable: true},
+		{name: "c_d", table: "c", references: "d", onDelete: "a", deferrable: true},
+		{name: "d_c", table: "d", references: "c", onDelete: "a", deferrable: true},
+		{name: "c_a", table: "c", references: "a", onDelete: "a"},
+	}
+
+	plan := planDelete([]string{"a", "b", "c", "d"}, fks)
+	if len(plan.blocking) != 0 {
+		t.Errorf("Expected a key between two cycles not to block, got %v", keyNames(plan.blocking))
+	}
+	if slices.ContainsFunc(plan.deferred, func(fk foreignKey) bool { return fk.name == "c_a" }) {
+		t.Errorf("Expected the key between two cycles not to be deferred, got %v", keyNames(plan.deferred))
+	}
+}
+
+func TestConstraintQualifiedNames(t *testing.T) {
+	fks := []foreignKey{
+		{name: "manager_fk", schema: "public", table: "departments"},
+		{name: "manager_fk", schema: "public", table: "teams"},
+		{name: "Lead", schema: `"Billing"`, table: `"Billing".accounts`},
+	}
+
+	got := constraintQualifiedNames(fks)
+	want := []string{`public."manager_fk"`, `"Billing"."Lead"`}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestReferencingClosure(t *testing.T) {
+	fks := []foreignKey{
+		{name: "posts_user_fk", table: "posts", references: "users"},
+		{name: "comments_post_fk", table: "comments", references: "posts"},
+		{name: "likes_comment_fk", table: "likes", references: "comments"},
+		{name: "orders_customer_fk", table: "orders", references: "customers"},
+	}
+
+	got := referencingClosure([]string{"posts"}, fks)
+	want := []string{"comments", "likes", "posts"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected closure %v, got %v", want, got)
+	}
+}
+
+func TestCleanClosure(t *testing.T) {
+	fks := []foreignKey{
+		{name: "posts_user_fk", table: "posts", tableSchema: "public", tableName: "posts", references: "users"},
+		{name: "comments_post_fk", table: "comments", tableSchema: "public", tableName: "comments", references: "posts"},
+		{name: "countries_region_fk", table: "countries", tableSchema: "public", tableName: "countries", references: "regions"},
+		{name: "pins_post_fk", table: "pins", tableSchema: "public", tableName: "pins", references: "posts"},
+	}
+	options := CleanOptions{PreserveTables: []string{"countries", "pins"}}
+
+	closure, conflicts := cleanClosure([]string{"users"}, fks, options.preserves)
+	if want := []string{"comments", "posts", "users"}; !slices.Equal(closure, want) {
+		t.Errorf("Expected closure %v, got %v", want, closure)
+	}
+	if want := []string{`pins."pins_post_fk"`}; !slices.Equal(conflicts, want) {
+		t.Errorf("Expected conflicts %v, got %v", want, conflicts)
+	}
+
+	// A preserved table referencing a table outside the closure is no conflict
+	if _, conflicts := cleanClosure([]string{"comments"}, fks, options.preserves); len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %v", conflicts)
+	}
+}
+
+func TestCleanOptions(t *testing.T) {
+	tc := &PostgreSQLTestContainer{config: &PostgreSQLConfig{Cleaning: CleanOptions{Mode: CleanDelete}}}
+
+	options, err := tc.cleanOptions(nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if options.Mode != CleanDelete {
+		t.Errorf("Expected the container's mode delete, got %s", options.Mode)
+	}
+
+	options, err = tc.cleanOptions([]CleanOption{CleanWithMode(CleanTruncate)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if options.Mode != CleanTruncate {
+		t.Errorf("Expected the per-call mode truncate, got %s", options.Mode)
+	}
+
+	_, err = tc.cleanOptions([]CleanOption{CleanWithMode("vacuum")})
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for an unknown mode, got %v", err)
+	}
+
+	if options, err := (&PostgreSQLTestContainer{}).cleanOptions(nil); err != nil || options.Mode != "" {
+		t.Errorf("Expected default options without a config, got %+v, %v", options, err)
+	}
+}
+
+func TestCleanWithRestartIdentity(t *testing.T) {
+	tc := &PostgreSQLTestContainer{config: &PostgreSQLConfig{Cleaning: CleanOptions{Mode: CleanDelete}}}
+
+	options, err := tc.cleanOptions([]CleanOption{CleanWithRestartIdentity()})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !options.RestartIdentity {
+		t.Error("Expected RestartIdentity to be set")
+	}
+	if options.Mode != CleanDelete {
+		t.Errorf("Expected the container's mode to be kept, got %s", options.Mode)
+	}
+}
+
+func TestWithCleaning(t *testing.T) {
+	config, err := buildConfig(WithCleaning(CleanOptions{Mode: CleanDelete}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.Cleaning.Mode != CleanDelete {
+		t.Errorf("Expected Cleaning.Mode to be delete, got %s", config.Cleaning.Mode)
+	}
+
+	if _, err := buildConfig(WithCleaning(CleanOptions{Mode: "vacuum"})); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestCleanOptions_Excludes(t *testing.T) {
+	options := CleanOptions{
+		ExcludeTables:   []string{"audit.*", "*_archive"},
+		ExcludePatterns: []*regexp.Regexp{regexp.MustCompile(`^outbox\.dead_letter_`)},
+		PreserveTables:  []string{"countries", "billing.currencies"},
+	}
+
+	tests := []struct {
+		schema    string
+		table     string
+		excluded  bool
+		preserved bool
+	}{
+		{schema: "audit", table: "events", excluded: true},
+		{schema: "public", table: "events"},
+		{schema: "billing", table: "orders_archive", excluded: true},
+		{schema: "outbox", table: "dead_letter_messages", excluded: true},
+		{schema: "outbox", table: "messages"},
+		{schema: "public", table: "countries", excluded: true, preserved: true},
+		{schema: "billing", table: "countries", excluded: true, preserved: true},
+		{schema: "billing", table: "currencies", excluded: true, preserved: true},
+		{schema: "public", table: "currencies"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema+"."+tt.table, func(t *testing.T) {
+			if got := options.excludes(tt.schema, tt.table); got != tt.excluded {
+				t.Errorf("Expected excludes to be %v, got %v", tt.excluded, got)
+			}
+			if got := options.preserves(tt.schema, tt.table); got != tt.preserved {
+				t.Errorf("Expected preserves to be %v, got %v", tt.preserved, got)
+			}
+		})
+	}
+}
+
+func TestCleanOptions_Lists(t *testing.T) {
+	tc := &PostgreSQLTestContainer{config: &PostgreSQLConfig{Cleaning: CleanOptions{
+		ExcludeTables:  make([]string, 1, 4),
+		PreserveTables: []string{"countries"},
+	}}}
+	tc.config.Cleaning.ExcludeTables[0] = "audit_*"
+
+	options, err := tc.cleanOptions(nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !slices.Equal(options.Schemas, []string{"public"}) {
+		t.Errorf("Expected the public schema by default, got %v", options.Schemas)
+	}
+	if !options.preserves("public", tc.migrationsTable()) {
+		t.Errorf("Expected the migrations table to be preserved, got %v", options.PreserveTables)
+	}
+	if !options.preserves("public", "countries") {
+		t.Errorf("Expected countries to be preserved, got %v", options.PreserveTables)
+	}
+
+	options, err = tc.cleanOptions([]CleanOption{
+		CleanWithSchemas("billing", "audit"),
+		CleanExcluding("billing.tmp_*"),
+		CleanExcludingPattern(regexp.MustCompile(`_archive$`)),
+		CleanPreserving("billing.currencies"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !slices.Equal(options.Schemas, []string{"billing", "audit"}) {
+		t.Errorf("Expected the per-call schemas, got %v", options.Schemas)
+	}
+	if !slices.Equal(options.ExcludeTables, []string{"audit_*", "billing.tmp_*"}) {
+		t.Errorf("Expected the per-call exclusion to be appended, got %v", options.ExcludeTables)
+	}
+	if !options.excludes("billing", "orders_archive") || !options.preserves("billing", "currencies") {
+		t.Errorf("Expected the per-call pattern and preserved table to apply, got %+v", options)
+	}
+
+	// Per-call options must not leak into the container's defaults
+	if got := tc.config.Cleaning.ExcludeTables[:2][1]; got != "" {
+		t.Errorf("Expected the container's exclusions to be untouched, got %q", got)
+	}
+	if len(tc.config.Cleaning.PreserveTables) != 1 {
+		t.Errorf("Expected the container's preserved tables to be untouched, got %v", tc.config.Cleaning.PreserveTables)
+	}
+}
+
+func TestValidateCleanOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options CleanOptions
+		wantErr bool
+	}{
+		{name: "empty", options: CleanOptions{}},
+		{
+			name: "valid lists",
+			options: CleanOptions{
+				Schemas:         []string{"billing"},
+				ExcludeTables:   []string{"audit.*"},
+				ExcludePatterns: []*regexp.Regexp{regexp.MustCompile(`^x`)},
+				PreserveTables:  []string{"countries"},
+			},
+		},
+		{name: "empty schema", options: CleanOptions{Schemas: []string{""}}, wantErr: true},
+		{name: "malformed glob", options: CleanOptions{ExcludeTables: []string{"audit.["}}, wantErr: true},
+		{name: "empty preserved table", options: CleanOptions{PreserveTables: []string{""}}, wantErr: true},
+		{name: "nil pattern", options: CleanOptions{ExcludePatterns: []*regexp.Regexp{nil}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCleanOptions(tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateCleanOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+}
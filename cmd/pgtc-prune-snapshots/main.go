@@ -0,0 +1,35 @@
+// Command pgtc-prune-snapshots removes stale snapshot images built by the SnapshotCache option.
+//
+//	go run github.com/JohnPlummer/jp-go-testcontainers-postgres/cmd/pgtc-prune-snapshots -older-than 72h
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	postgres "github.com/JohnPlummer/jp-go-testcontainers-postgres"
+)
+
+func main() {
+	olderThan := flag.Duration("older-than", 7*24*time.Hour, "remove snapshots created longer ago than this; 0 removes all")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	removed, err := postgres.PruneSnapshotCache(ctx, *olderThan)
+	for _, ref := range removed {
+		fmt.Printf("Removed %s\n", ref)
+	}
+	if err != nil {
+		log.Fatalf("Failed to prune snapshot images: %v", err)
+	}
+	if len(removed) == 0 {
+		fmt.Println("No stale snapshot images found")
+	}
+}
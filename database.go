@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TestDatabase is a database created inside the container for a single test
+type TestDatabase struct {
+	Name       string
+	QuotedName string // Name quoted for use as an SQL identifier
+	URL        string
+	Pool       *pgxpool.Pool
+
+	tc       *PostgreSQLTestContainer
+	dropOnce sync.Once
+	dropErr  error
+}
+
+// NewTestDatabase creates a new, empty database within the container for isolation.
+// This is useful when you need multiple isolated databases in the same container.
+// Any name up to 63 bytes is accepted, including dashes and mixed case.
+// Call Drop when done; Close reports and drops databases that are left over.
+func (tc *PostgreSQLTestContainer) NewTestDatabase(dbName string) (*TestDatabase, error) {
+	if dbName == "" || len(dbName) > maxIdentifierLength {
+		return nil, fmt.Errorf("invalid test database name %q: must be 1 to %d bytes", dbName, maxIdentifierLength)
+	}
+
+	quoted := pgx.Identifier{dbName}.Sanitize()
+	if _, err := tc.Pool.Exec(tc.Context, "CREATE DATABASE "+quoted); err != nil {
+		return nil, fmt.Errorf("failed to create test database %s: %w", dbName, err)
+	}
+
+	databaseURL, err := tc.databaseURLFor(dbName)
+	if err != nil {
+		tc.dropDatabase(tc.Context, dbName)
+		return nil, err
+	}
+
+	pool, err := newPool(tc.Context, databaseURL, tc.config)
+	if err != nil {
+		tc.dropDatabase(tc.Context, dbName)
+		return nil, fmt.Errorf("failed to connect to test database %s: %w", dbName, err)
+	}
+
+	return tc.trackDatabase(dbName, databaseURL, pool), nil
+}
+
+// Drop closes the database's pool, terminates its remaining sessions and drops it.
+// It is safe to call more than once.
+func (db *TestDatabase) Drop(ctx context.Context) error {
+	db.dropOnce.Do(func() {
+		if db.Pool != nil {
+			db.Pool.Close()
+		}
+		db.dropErr = db.tc.terminateAndDrop(ctx, db.Name)
+		if db.dropErr == nil {
+			db.tc.untrackDatabase(db.Name)
+		}
+	})
+	return db.dropErr
+}
+
+// trackDatabase registers a database created by the container until it is dropped
+func (tc *PostgreSQLTestContainer) trackDatabase(name, databaseURL string, pool *pgxpool.Pool) *TestDatabase {
+	db := &TestDatabase{
+		Name:       name,
+		QuotedName: pgx.Identifier{name}.Sanitize(),
+		URL:        databaseURL,
+		Pool:       pool,
+		tc:         tc,
+	}
+
+	tc.databasesMu.Lock()
+	defer tc.databasesMu.Unlock()
+	if tc.databases == nil {
+		tc.databases = make(map[string]*TestDatabase)
+	}
+	tc.databases[name] = db
+
+	return db
+}
+
+// untrackDatabase forgets a dropped database
+func (tc *PostgreSQLTestContainer) untrackDatabase(name string) {
+	tc.databasesMu.Lock()
+	defer tc.databasesMu.Unlock()
+	delete(tc.databases, name)
+}
+
+// leakedDatabases returns the databases created by the container that were not dropped, by name
+func (tc *PostgreSQLTestContainer) leakedDatabases() []*TestDatabase {
+	tc.databasesMu.Lock()
+	defer tc.databasesMu.Unlock()
+
+	leaked := make([]*TestDatabase, 0, len(tc.databases))
+	for _, db := range tc.databases {
+		leaked = append(leaked, db)
+	}
+	slices.SortFunc(leaked, func(a, b *TestDatabase) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return leaked
+}
+
+// dropLeakedDatabases reports and drops the databases that were not dropped by their tests
+func (tc *PostgreSQLTestContainer) dropLeakedDatabases(ctx context.Context) []error {
+	leaked := tc.leakedDatabases()
+	if len(leaked) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(leaked))
+	for i, db := range leaked {
+		names[i] = db.Name
+	}
+	tc.logf("Warning: %d test databases were not dropped: %s", len(leaked), strings.Join(names, ", "))
+
+	var errs []error
+	for _, db := range leaked {
+		if err := db.Drop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// terminateAndDrop disconnects every session of a database and drops it
+func (tc *PostgreSQLTestContainer) terminateAndDrop(ctx context.Context, name string) error {
+	_, err := tc.Pool.Exec(ctx, `
+		SELECT pg_terminate_backend(pid)
+		FROM pg_stat_activity
+		WHERE datname = $1 AND pid <> pg_backend_pid()
+	`, name)
+	if err != nil {
+		return fmt.Errorf("failed to terminate sessions of %s: %w", name, err)
+	}
+
+	return tc.dropDatabaseErr(ctx, name)
+}
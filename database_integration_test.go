@@ -0,0 +1,89 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewTestDatabase_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var warnings []string
+	config, err := buildConfig(WithImageFamily(ImageFamilyPostgres, ""), WithLogf(func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}))
+	if err != nil {
+		t.Fatalf("Failed to build config: %v", err)
+	}
+
+	// Closed explicitly below to check the leak report
+	tc, err := StartPostgreSQLContainer(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to start PostgreSQL container: %v", err)
+	}
+
+	// Dashes and mixed case need quoting
+	db, err := tc.NewTestDatabase("Orders-Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	if db.QuotedName != `"Orders-Test"` {
+		t.Errorf("Expected quoted name \"Orders-Test\", got %s", db.QuotedName)
+	}
+
+	var current string
+	if err := db.Pool.QueryRow(ctx, "SELECT current_database()").Scan(&current); err != nil {
+		t.Fatalf("Failed to query test database: %v", err)
+	}
+	if current != "Orders-Test" {
+		t.Errorf("Expected to be connected to Orders-Test, got %s", current)
+	}
+
+	// An open session outside the handle's pool must not block the drop
+	conn, err := newPool(ctx, db.URL, tc.config)
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.Ping(ctx); err != nil {
+		t.Fatalf("Failed to ping test database: %v", err)
+	}
+
+	if err := db.Drop(ctx); err != nil {
+		t.Fatalf("Failed to drop test database: %v", err)
+	}
+	if err := db.Drop(ctx); err != nil {
+		t.Errorf("Expected a second Drop to be a no-op, got %v", err)
+	}
+
+	var exists bool
+	if err := tc.Pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", "Orders-Test").Scan(&exists); err != nil {
+		t.Fatalf("Failed to query pg_database: %v", err)
+	}
+	if exists {
+		t.Error("Expected Orders-Test to be dropped")
+	}
+
+	// Close reports and drops databases that were never dropped
+	if _, err := tc.NewTestDatabase("leaked_db"); err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	if err := tc.Close(); err != nil {
+		t.Fatalf("Failed to close container: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.ContainsFunc(warnings, func(w string) bool { return strings.Contains(w, "leaked_db") }) {
+		t.Errorf("Expected Close to report leaked_db, got %v", warnings)
+	}
+}
@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewTestDatabase_InvalidName(t *testing.T) {
+	tc := &PostgreSQLTestContainer{}
+
+	for _, name := range []string{"", strings.Repeat("a", maxIdentifierLength+1)} {
+		if _, err := tc.NewTestDatabase(name); err == nil {
+			t.Errorf("Expected an error for name %q", name)
+		}
+	}
+}
+
+func TestTrackDatabase(t *testing.T) {
+	tc := &PostgreSQLTestContainer{}
+
+	db := tc.trackDatabase("My-Db", "postgres://localhost/My-Db", nil)
+	if db.QuotedName != `"My-Db"` {
+		t.Errorf("Expected quoted name \"My-Db\", got %s", db.QuotedName)
+	}
+	tc.trackDatabase("another", "postgres://localhost/another", nil)
+
+	leaked := tc.leakedDatabases()
+	if len(leaked) != 2 || leaked[0].Name != "My-Db" || leaked[1].Name != "another" {
+		t.Errorf("Expected both databases to be reported in name order, got %v", leaked)
+	}
+
+	tc.untrackDatabase("My-Db")
+	if leaked := tc.leakedDatabases(); len(leaked) != 1 || leaked[0].Name != "another" {
+		t.Errorf("Expected only another to be reported, got %v", leaked)
+	}
+}
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/system"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// dockerCheckTimeout bounds the Docker availability check
+const dockerCheckTimeout = 15 * time.Second
+
+// ContainerRuntime identifies the engine behind the Docker API
+type ContainerRuntime string
+
+const (
+	RuntimeDocker         ContainerRuntime = "docker"
+	RuntimeDockerDesktop  ContainerRuntime = "docker-desktop"
+	RuntimePodman         ContainerRuntime = "podman"
+	RuntimeColima         ContainerRuntime = "colima"
+	RuntimeRancherDesktop ContainerRuntime = "rancher-desktop"
+	RuntimeOrbStack       ContainerRuntime = "orbstack"
+	RuntimeUnknown        ContainerRuntime = "unknown"
+)
+
+// DockerAvailabilityResult holds information about Docker availability
+type DockerAvailabilityResult struct {
+	Available bool
+	Reason    string
+	Error     error
+
+	// Engine details, set when the engine answered
+	Host            string           // Resolved Docker host, e.g. unix:///var/run/docker.sock
+	ServerVersion   string           // Engine version
+	APIVersion      string           // Negotiated API version
+	OperatingSystem string           // e.g. "Docker Desktop" or "Ubuntu 24.04.1 LTS"
+	OSType          string           // linux or windows
+	Architecture    string           // e.g. x86_64 or aarch64
+	Rootless        bool             // The engine runs without root privileges
+	Runtime         ContainerRuntime // Engine behind the Docker API
+	Latency         time.Duration    // Round trip of a ping to the engine
+}
+
+// dockerInfoClient is the part of the Docker API client used by the availability check
+type dockerInfoClient interface {
+	Info(ctx context.Context) (system.Info, error)
+	ServerVersion(ctx context.Context) (types.Version, error)
+	Ping(ctx context.Context) (types.Ping, error)
+	DaemonHost() string
+	ClientVersion() string
+	Close() error
+}
+
+// dockerAvailability caches the availability check for the lifetime of the process
+var dockerAvailability = sync.OnceValue(func() DockerAvailabilityResult {
+	containerRuntime() // Configures testcontainers before the check below uses it
+
+	ctx, cancel := context.WithTimeout(context.Background(), dockerCheckTimeout)
+	defer cancel()
+	return checkDockerAvailability(ctx, newDockerInfoClient)
+})
+
+// CheckDockerAvailability checks if Docker is available and running.
+// It talks to the engine through the Docker API, so only a socket is needed, not the docker CLI.
+// DOCKER_HOST, testcontainers properties and rootless sockets are honoured. The result is
+// cached for the lifetime of the process.
+func CheckDockerAvailability() DockerAvailabilityResult {
+	return dockerAvailability()
+}
+
+// newDockerInfoClient creates a Docker API client the way testcontainers does
+func newDockerInfoClient(ctx context.Context) (dockerInfoClient, error) {
+	return testcontainers.NewDockerClientWithOpts(ctx)
+}
+
+// checkDockerAvailability queries the engine through a client created by newClient
+func checkDockerAvailability(ctx context.Context, newClient func(context.Context) (dockerInfoClient, error)) (result DockerAvailabilityResult) {
+	// testcontainers panics when no Docker host can be found
+	defer func() {
+		if r := recover(); r != nil {
+			result = DockerAvailabilityResult{
+				Available: false,
+				Reason:    "Docker host could not be found",
+				Error:     fmt.Errorf("%v", r),
+			}
+		}
+	}()
+
+	cli, err := newClient(ctx)
+	if err != nil {
+		return DockerAvailabilityResult{
+			Available: false,
+			Reason:    "Docker client could not be created",
+			Error:     err,
+		}
+	}
+	defer cli.Close()
+
+	start := time.Now()
+	if _, err := cli.Ping(ctx); err != nil {
+		return DockerAvailabilityResult{
+			Available: false,
+			Reason:    "Docker daemon is not running or accessible",
+			Error:     err,
+			Host:      cli.DaemonHost(),
+		}
+	}
+	latency := time.Since(start)
+
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return DockerAvailabilityResult{
+			Available: false,
+			Reason:    "Docker is running but info request failed",
+			Error:     err,
+			Host:      cli.DaemonHost(),
+		}
+	}
+
+	// Podman identifies itself in the version components only; without them detection falls back to info
+	version, _ := cli.ServerVersion(ctx)
+
+	return DockerAvailabilityResult{
+		Available:       true,
+		Reason:          "Docker is available and running",
+		Error:           nil,
+		Host:            cli.DaemonHost(),
+		ServerVersion:   info.ServerVersion,
+		APIVersion:      cli.ClientVersion(),
+		OperatingSystem: info.OperatingSystem,
+		OSType:          info.OSType,
+		Architecture:    info.Architecture,
+		Rootless:        slices.Contains(info.SecurityOptions, "name=rootless"),
+		Runtime:         detectRuntime(info, version, cli.DaemonHost()),
+		Latency:         latency,
+	}
+}
+
+// detectRuntime identifies the engine from its info, version and host
+func detectRuntime(info system.Info, version types.Version, host string) ContainerRuntime {
+	for _, component := range version.Components {
+		if strings.Contains(strings.ToLower(component.Name), "podman") {
+			return RuntimePodman
+		}
+	}
+
+	operatingSystem := strings.ToLower(info.OperatingSystem)
+	switch {
+	case strings.Contains(strings.ToLower(version.Platform.Name), "podman"), strings.Contains(host, "podman"):
+		return RuntimePodman
+	case strings.Contains(operatingSystem, "docker desktop"):
+		return RuntimeDockerDesktop
+	case strings.Contains(operatingSystem, "orbstack"), strings.Contains(host, ".orbstack"):
+		return RuntimeOrbStack
+	case strings.Contains(operatingSystem, "rancher desktop"), strings.Contains(host, ".rd/"):
+		return RuntimeRancherDesktop
+	case info.Name == "colima", strings.Contains(host, ".colima"):
+		return RuntimeColima
+	case info.ServerVersion != "":
+		return RuntimeDocker
+	}
+	return RuntimeUnknown
+}
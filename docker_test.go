@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/system"
+)
+
+// fakeDockerClient answers the availability check without an engine
+type fakeDockerClient struct {
+	info    system.Info
+	version types.Version
+	pingErr error
+	infoErr error
+	host    string
+}
+
+func (c *fakeDockerClient) Info(ctx context.Context) (system.Info, error) {
+	return c.info, c.infoErr
+}
+
+func (c *fakeDockerClient) ServerVersion(ctx context.Context) (types.Version, error) {
+	return c.version, nil
+}
+
+func (c *fakeDockerClient) Ping(ctx context.Context) (types.Ping, error) {
+	return types.Ping{}, c.pingErr
+}
+
+func (c *fakeDockerClient) DaemonHost() string {
+	return c.host
+}
+
+func (c *fakeDockerClient) ClientVersion() string {
+	return "1.47"
+}
+
+func (c *fakeDockerClient) Close() error {
+	return nil
+}
+
+func TestCheckDockerAvailability_Engine(t *testing.T) {
+	cli := &fakeDockerClient{
+		info: system.Info{
+			ServerVersion:   "27.3.1",
+			OperatingSystem: "Ubuntu 24.04.1 LTS",
+			OSType:          "linux",
+			Architecture:    "x86_64",
+			SecurityOptions: []string{"name=seccomp,profile=builtin", "name=rootless"},
+		},
+		host: "unix:///run/user/1000/docker.sock",
+	}
+
+	result := checkDockerAvailability(context.Background(), func(context.Context) (dockerInfoClient, error) {
+		return cli, nil
+	})
+
+	if !result.Available {
+		t.Fatalf("Expected Docker to be available, got %+v", result)
+	}
+	if result.Reason != "Docker is available and running" {
+		t.Errorf("Expected reason to be 'Docker is available and running', got %s", result.Reason)
+	}
+	if result.ServerVersion != "27.3.1" || result.APIVersion != "1.47" {
+		t.Errorf("Expected versions 27.3.1 and 1.47, got %s and %s", result.ServerVersion, result.APIVersion)
+	}
+	if result.OSType != "linux" || result.Architecture != "x86_64" {
+		t.Errorf("Expected linux/x86_64, got %s/%s", result.OSType, result.Architecture)
+	}
+	if !result.Rootless {
+		t.Error("Expected rootless to be detected")
+	}
+	if result.Runtime != RuntimeDocker {
+		t.Errorf("Expected runtime docker, got %s", result.Runtime)
+	}
+	if result.Host != cli.host {
+		t.Errorf("Expected host %s, got %s", cli.host, result.Host)
+	}
+}
+
+func TestCheckDockerAvailability_Unavailable(t *testing.T) {
+	tests := []struct {
+		name       string
+		newClient  func(context.Context) (dockerInfoClient, error)
+		wantReason string
+	}{
+		{
+			name: "no docker host",
+			newClient: func(context.Context) (dockerInfoClient, error) {
+				panic("rootless Docker not found")
+			},
+			wantReason: "Docker host could not be found",
+		},
+		{
+			name: "client error",
+			newClient: func(context.Context) (dockerInfoClient, error) {
+				return nil, errors.New("bad DOCKER_HOST")
+			},
+			wantReason: "Docker client could not be created",
+		},
+		{
+			name: "daemon down",
+			newClient: func(context.Context) (dockerInfoClient, error) {
+				return &fakeDockerClient{pingErr: errors.New("connection refused")}, nil
+			},
+			wantReason: "Docker daemon is not running or accessible",
+		},
+		{
+			name: "info fails",
+			newClient: func(context.Context) (dockerInfoClient, error) {
+				return &fakeDockerClient{infoErr: errors.New("permission denied")}, nil
+			},
+			wantReason: "Docker is running but info request failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := checkDockerAvailability(context.Background(), tt.newClient)
+			if result.Available {
+				t.Fatal("Expected Docker to be unavailable")
+			}
+			if result.Reason != tt.wantReason {
+				t.Errorf("Expected reason %q, got %q", tt.wantReason, result.Reason)
+			}
+			if result.Error == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestDetectRuntime(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    system.Info
+		version types.Version
+		host    string
+		want    ContainerRuntime
+	}{
+		{
+			name: "docker engine",
+			info: system.Info{ServerVersion: "27.3.1", OperatingSystem: "Ubuntu 24.04.1 LTS"},
+			host: "unix:///var/run/docker.sock",
+			want: RuntimeDocker,
+		},
+		{
+			name:    "podman",
+			info:    system.Info{ServerVersion: "5.2.2", OperatingSystem: "fedora"},
+			version: types.Version{Components: []types.ComponentVersion{{Name: "Podman Engine", Version: "5.2.2"}}},
+			host:    "unix:///run/user/1000/podman/podman.sock",
+			want:    RuntimePodman,
+		},
+		{
+			name: "podman socket without components",
+			info: system.Info{ServerVersion: "4.9.3"},
+			host: "unix:///run/podman/podman.sock",
+			want: RuntimePodman,
+		},
+		{
+			name: "docker desktop",
+			info: system.Info{ServerVersion: "27.2.0", OperatingSystem: "Docker Desktop"},
+			want: RuntimeDockerDesktop,
+		},
+		{
+			name: "colima",
+			info: system.Info{ServerVersion: "27.1.1", Name: "colima"},
+			host: "unix:///Users/dev/.colima/default/docker.sock",
+			want: RuntimeColima,
+		},
+		{
+			name: "rancher desktop",
+			info: system.Info{ServerVersion: "26.1.5"},
+			host: "unix:///Users/dev/.rd/docker.sock",
+			want: RuntimeRancherDesktop,
+		},
+		{
+			name: "orbstack",
+			info: system.Info{ServerVersion: "27.3.1", OperatingSystem: "OrbStack"},
+			want: RuntimeOrbStack,
+		},
+		{
+			name: "unknown",
+			want: RuntimeUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectRuntime(tt.info, tt.version, tt.host); got != tt.want {
+				t.Errorf("Expected runtime %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Extension is a PostgreSQL extension installed on startup with CREATE EXTENSION
+type Extension struct {
+	Name    string // e.g., "pg_trgm"
+	Version string // Optional; the extension's default version if empty
+	Schema  string // Optional; created if it does not exist
+}
+
+// ExtensionError reports an extension that the image does not provide.
+// It matches ErrExtensionNotAvailable with errors.Is.
+type ExtensionError struct {
+	Extension Extension
+	Available []string // Extensions (or versions, when Version was not found) the image provides
+}
+
+func (e *ExtensionError) Error() string {
+	if e.Extension.Version != "" {
+		return fmt.Sprintf("%v: %s version %s (available versions: %s)",
+			ErrExtensionNotAvailable, e.Extension.Name, e.Extension.Version, strings.Join(e.Available, ", "))
+	}
+	return fmt.Sprintf("%v: %s (available: %s)", ErrExtensionNotAvailable, e.Extension.Name, strings.Join(e.Available, ", "))
+}
+
+// Is reports whether target is ErrExtensionNotAvailable
+func (e *ExtensionError) Is(target error) bool {
+	return target == ErrExtensionNotAvailable
+}
+
+// installExtensions creates each extension (and its schema) in the database behind db.
+// Availability is checked first so a missing extension is reported with what the image offers.
+func installExtensions(ctx context.Context, db DBTX, extensions []Extension) error {
+	if len(extensions) == 0 {
+		return nil
+	}
+
+	available, err := availableExtensions(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, ext := range extensions {
+		versions, ok := available[ext.Name]
+		if !ok {
+			return &ExtensionError{Extension: ext, Available: sortedKeys(available)}
+		}
+		if ext.Version != "" && !slices.Contains(versions, ext.Version) {
+			return &ExtensionError{Extension: ext, Available: versions}
+		}
+
+		stmt := "CREATE EXTENSION IF NOT EXISTS " + pgx.Identifier{ext.Name}.Sanitize()
+		if ext.Schema != "" {
+			schema := pgx.Identifier{ext.Schema}.Sanitize()
+			if _, err := db.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+schema); err != nil {
+				return fmt.Errorf("failed to create schema %s for extension %s: %w", ext.Schema, ext.Name, err)
+			}
+			stmt += " SCHEMA " + schema
+		}
+		if ext.Version != "" {
+			stmt += " VERSION " + pgx.Identifier{ext.Version}.Sanitize()
+		}
+		stmt += " CASCADE"
+
+		if _, err := db.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create extension %s: %w", ext.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// availableExtensions maps every extension the server can install to its available versions
+func availableExtensions(ctx context.Context, db DBTX) (map[string][]string, error) {
+	rows, err := db.Query(ctx, `
+		SELECT name, version
+		FROM pg_available_extension_versions
+		ORDER BY name, version
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list available extensions: %w", err)
+	}
+	defer rows.Close()
+
+	available := make(map[string][]string)
+	for rows.Next() {
+		var name, version string
+		if err := rows.Scan(&name, &version); err != nil {
+			return nil, fmt.Errorf("failed to scan available extension: %w", err)
+		}
+		available[name] = append(available[name], version)
+	}
+
+	return available, rows.Err()
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,64 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtensions_InstalledBeforeMigrations(t *testing.T) {
+	migrationsDir := filepath.Join(t.TempDir(), "migrations")
+	if err := os.MkdirAll(migrationsDir, 0o755); err != nil {
+		t.Fatalf("Failed to create migrations directory: %v", err)
+	}
+	up := "CREATE TABLE tags (name CITEXT PRIMARY KEY);"
+	if err := os.WriteFile(filepath.Join(migrationsDir, "001_create_tags.up.sql"), []byte(up), 0o644); err != nil {
+		t.Fatalf("Failed to write migration file: %v", err)
+	}
+
+	tc := New(t,
+		WithExtensions(Extension{Name: "citext"}, Extension{Name: "pg_trgm", Schema: "extensions"}),
+		WithMigrations(migrationsDir),
+	)
+
+	var schema string
+	err := tc.Pool.QueryRow(context.Background(), `
+		SELECT n.nspname
+		FROM pg_extension e
+		JOIN pg_namespace n ON n.oid = e.extnamespace
+		WHERE e.extname = 'pg_trgm'
+	`).Scan(&schema)
+	if err != nil {
+		t.Fatalf("Failed to query pg_trgm extension: %v", err)
+	}
+	if schema != "extensions" {
+		t.Errorf("Expected pg_trgm to be installed in extensions, got %s", schema)
+	}
+}
+
+func TestExtensions_NotAvailable(t *testing.T) {
+	if skip, msg := SkipIfDockerUnavailable(); skip {
+		t.Skip(msg)
+	}
+
+	config := DefaultPostgreSQLConfig()
+	config.Extensions = []Extension{{Name: "no_such_extension"}}
+
+	tc, err := StartPostgreSQLContainer(context.Background(), config)
+	if err == nil {
+		tc.Close()
+		t.Fatal("Expected error for missing extension")
+	}
+
+	var extErr *ExtensionError
+	if !errors.As(err, &extErr) {
+		t.Fatalf("Expected ExtensionError, got %v", err)
+	}
+	if len(extErr.Available) == 0 {
+		t.Error("Expected available extensions to be listed")
+	}
+}
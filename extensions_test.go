@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExtensionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *ExtensionError
+		want string
+	}{
+		{
+			name: "missing extension",
+			err:  &ExtensionError{Extension: Extension{Name: "vector"}, Available: []string{"citext", "hstore"}},
+			want: "PostgreSQL extension not available: vector (available: citext, hstore)",
+		},
+		{
+			name: "missing version",
+			err:  &ExtensionError{Extension: Extension{Name: "hstore", Version: "9.9"}, Available: []string{"1.7", "1.8"}},
+			want: "PostgreSQL extension not available: hstore version 9.9 (available versions: 1.7, 1.8)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err.Error() != tt.want {
+				t.Errorf("Error message = %v, want %v", tt.err.Error(), tt.want)
+			}
+			if !errors.Is(tt.err, ErrExtensionNotAvailable) {
+				t.Error("Expected ExtensionError to match ErrExtensionNotAvailable")
+			}
+		})
+	}
+}
+
+func TestWithExtensions(t *testing.T) {
+	config, err := buildConfig(
+		WithExtensions(Extension{Name: "pg_trgm"}),
+		WithExtensions(Extension{Name: "hstore", Schema: "extensions"}),
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(config.Extensions) != 2 {
+		t.Fatalf("Expected 2 extensions, got %d", len(config.Extensions))
+	}
+	if config.Extensions[1].Schema != "extensions" {
+		t.Errorf("Expected schema to be extensions, got %s", config.Extensions[1].Schema)
+	}
+
+	if _, err := buildConfig(WithExtensions(Extension{})); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for unnamed extension, got %v", err)
+	}
+	if _, err := buildConfig(WithExtensions(Extension{Name: "citext", Schema: strings.Repeat("s", 64)})); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for long schema, got %v", err)
+	}
+}
+
+func TestConfigFingerprint_Extensions(t *testing.T) {
+	a := DefaultPostgreSQLConfig()
+	b := DefaultPostgreSQLConfig()
+	b.Extensions = []Extension{{Name: "citext"}}
+
+	if configFingerprint(a) == configFingerprint(b) {
+		t.Error("Expected configurations with different extensions to have different fingerprints")
+	}
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ExternalDatabaseURLEnv names the environment variable pointing at an existing PostgreSQL
+// server to use instead of a container, when PostgreSQLConfig.ExternalDatabaseURL is empty
+const ExternalDatabaseURLEnv = "TEST_DATABASE_URL"
+
+// externalDatabaseURL returns the existing server to use, or "" to start a container
+func (c *PostgreSQLConfig) externalDatabaseURL() string {
+	if c.ExternalDatabaseURL != "" {
+		return c.ExternalDatabaseURL
+	}
+	return os.Getenv(ExternalDatabaseURLEnv)
+}
+
+// startExternalDatabase creates a uniquely named database on an existing server and prepares it
+// like a container's database. Close drops the database, and any template prepared from it,
+// instead of terminating a container.
+func startExternalDatabase(ctx context.Context, config *PostgreSQLConfig) (*PostgreSQLTestContainer, error) {
+	adminURL := config.externalDatabaseURL()
+	if err := validateExternalDatabaseURL(adminURL); err != nil {
+		return nil, err
+	}
+	connConfig, err := pgx.ParseConfig(adminURL)
+	if err != nil {
+		return nil, &ConfigError{Field: "ExternalDatabaseURL", Value: redactURL(adminURL), Reason: "is not a valid connection string"}
+	}
+
+	// The server connects to the database named after the user when the URL names none
+	adminDatabase := connConfig.Database
+	if adminDatabase == "" {
+		adminDatabase = connConfig.User
+	}
+
+	databaseName, err := externalDatabaseName(config.DatabaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := pgx.ConnectConfig(ctx, connConfig)
+	if err != nil {
+		return nil, &StartupError{Phase: PhasePing, Err: fmt.Errorf("%w: %v", ErrDatabaseConnFailed, err)}
+	}
+	_, err = conn.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{databaseName}.Sanitize())
+	conn.Close(ctx)
+	if err != nil {
+		return nil, &StartupError{Phase: PhaseCreate, Err: fmt.Errorf("failed to create external test database %s: %w", databaseName, err)}
+	}
+
+	tc := &PostgreSQLTestContainer{
+		DatabaseURL:   adminURL,
+		Context:       ctx,
+		DatabaseName:  databaseName,
+		Username:      connConfig.User,
+		Password:      connConfig.Password,
+		config:        config,
+		adminDatabase: adminDatabase,
+	}
+	tc.DatabaseURL, err = tc.databaseURLFor(databaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	// fail reports a failure after the database was created and drops it
+	fail := func(phase StartupPhase, err error) (*PostgreSQLTestContainer, error) {
+		if tc.Pool != nil {
+			tc.Pool.Close()
+		}
+		_ = tc.dropPackageDatabase(context.WithoutCancel(ctx))
+		return nil, &StartupError{Phase: phase, Err: err}
+	}
+
+	tc.Pool, err = newPool(ctx, tc.DatabaseURL, config)
+	if err != nil {
+		tc.Pool = nil
+		return fail(PhasePool, err)
+	}
+	if err := tc.Pool.Ping(ctx); err != nil {
+		return fail(PhasePing, err)
+	}
+
+	if err := prepareDatabase(ctx, tc.Pool, tc.DatabaseURL, config, config.startupMigrator()); err != nil {
+		return fail(PhaseMigrate, err)
+	}
+
+	if config.Cleaning.TrackDirtyTables {
+		if err := installDirtyTracking(ctx, tc.Pool); err != nil {
+			return fail(PhaseMigrate, err)
+		}
+	}
+
+	return tc, nil
+}
+
+// externalDatabaseName returns a database name unique to this run, so concurrent runs against
+// the same server never share data
+func externalDatabaseName(base string) (string, error) {
+	random := make([]byte, 4)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate database name: %w", err)
+	}
+
+	suffix := fmt.Sprintf("_%d_%s", os.Getpid(), hex.EncodeToString(random))
+	if len(base)+len(suffix) > maxIdentifierLength {
+		base = base[:maxIdentifierLength-len(suffix)]
+	}
+	return base + suffix, nil
+}
+
+// validateExternalDatabaseURL checks that an external server is given as a postgres:// URL,
+// which the URLs of the test database and of cloned databases are derived from
+func validateExternalDatabaseURL(databaseURL string) error {
+	u, err := url.Parse(databaseURL)
+	if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+		return &ConfigError{Field: "ExternalDatabaseURL", Value: redactURL(databaseURL), Reason: "must be a postgres:// URL"}
+	}
+	return nil
+}
+
+// redactURL hides the password of a connection URL for use in error messages
+func redactURL(databaseURL string) string {
+	u, err := url.Parse(databaseURL)
+	if err != nil || u.Scheme == "" {
+		return "<connection string>"
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,64 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+)
+
+func TestExternalDatabase(t *testing.T) {
+	ctx := context.Background()
+
+	// A container stands in for the managed server
+	server := New(t, WithImageFamily(ImageFamilyPostgres, ""))
+
+	migrations := fstest.MapFS{
+		"001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id SERIAL PRIMARY KEY, name TEXT NOT NULL);")},
+		"001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	}
+
+	t.Setenv(ExternalDatabaseURLEnv, server.DatabaseURL)
+	config, err := buildConfig(WithMigrationsFS(migrations, "."))
+	if err != nil {
+		t.Fatalf("Failed to build config: %v", err)
+	}
+
+	tc, err := StartPostgreSQLContainerWithCheck(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to start against the external server: %v", err)
+	}
+
+	if tc.Container != nil {
+		t.Error("Expected no container in external mode")
+	}
+	if tc.DatabaseName == server.DatabaseName {
+		t.Errorf("Expected a uniquely named database, got %s", tc.DatabaseName)
+	}
+
+	if _, err := tc.Pool.Exec(ctx, "INSERT INTO users (name) VALUES ('alice')"); err != nil {
+		t.Fatalf("Expected migrations to have run: %v", err)
+	}
+
+	if err := tc.PrepareTemplate(ctx, ""); err != nil {
+		t.Fatalf("Failed to prepare template: %v", err)
+	}
+	templateName := tc.templateName
+
+	// Close drops the database and its template; the server keeps running
+	if err := tc.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	for _, name := range []string{tc.DatabaseName, templateName} {
+		var exists bool
+		err = server.Pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists)
+		if err != nil {
+			t.Fatalf("Failed to query pg_database: %v", err)
+		}
+		if exists {
+			t.Errorf("Expected %s to be dropped on Close", name)
+		}
+	}
+}
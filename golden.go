@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// UpdateGoldenFlag names the test flag that makes AssertSchemaGolden rewrite golden files:
+// go test -update. The package does not register it, so it cannot clash with a flag of the
+// same name; the test package defines it, as is usual for golden files.
+const UpdateGoldenFlag = "update"
+
+// UpdateGoldenEnv names the environment variable that also makes AssertSchemaGolden rewrite
+// golden files, for test packages that do not define the flag: UPDATE_GOLDEN=1 go test
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+// updateGolden reports whether the -update flag or UPDATE_GOLDEN is set to a true value
+func updateGolden() bool {
+	return updateGoldenIn(flag.CommandLine)
+}
+
+// updateGoldenIn reports whether the -update flag in flags or UPDATE_GOLDEN is set to a true value
+func updateGoldenIn(flags *flag.FlagSet) bool {
+	if f := flags.Lookup(UpdateGoldenFlag); f != nil {
+		if update, err := strconv.ParseBool(f.Value.String()); err == nil && update {
+			return true
+		}
+	}
+	update, _ := strconv.ParseBool(os.Getenv(UpdateGoldenEnv))
+	return update
+}
+
+// SchemaDump returns the schema of the container's database as normalised, deterministic text
+// built from pg_catalog: one sorted line per table, column, index, constraint, function, view,
+// enum and trigger. Objects owned by extensions and the migrations table are left out.
+func (tc *PostgreSQLTestContainer) SchemaDump(ctx context.Context) (string, error) {
+	snapshot, err := snapshotSchema(ctx, tc.Pool, []string{tc.migrationsTable()})
+	if err != nil {
+		return "", err
+	}
+	return snapshot.String(), nil
+}
+
+// AssertSchemaGolden compares SchemaDump with the golden file testdata/<name> and fails the
+// test with a diff when they differ. Run the tests with -update (or UPDATE_GOLDEN=1) to write the
+// golden file.
+func (tc *PostgreSQLTestContainer) AssertSchemaGolden(t testing.TB, name string) {
+	t.Helper()
+
+	dump, err := tc.SchemaDump(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to dump schema: %v", err)
+	}
+
+	path := filepath.Join("testdata", name)
+	if updateGolden() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(dump), 0o644); err != nil { // #nosec G306 -- golden files are checked in
+			t.Fatalf("Failed to write golden file %s: %v", path, err)
+		}
+		return
+	}
+
+	golden, err := os.ReadFile(path) // #nosec G304 -- path is chosen by the test
+	if os.IsNotExist(err) {
+		t.Fatalf("Golden file %s does not exist; run the test with -%s or %s=1 to create it", path, UpdateGoldenFlag, UpdateGoldenEnv)
+	}
+	if err != nil {
+		t.Fatalf("Failed to read golden file %s: %v", path, err)
+	}
+
+	want := parseSchemaDump(string(golden))
+	got := parseSchemaDump(dump)
+	if diff := diffSchemas(want, got); diff != "" {
+		t.Errorf("Schema does not match %s (- golden, + actual); run with -%s or %s=1 if the change is intended:\n%s", path, UpdateGoldenFlag, UpdateGoldenEnv, diff)
+	}
+}
+
+// parseSchemaDump turns a dump back into a snapshot, tolerating CRLF line endings from checkouts
+func parseSchemaDump(dump string) schemaSnapshot {
+	var snapshot schemaSnapshot
+	for _, line := range strings.Split(strings.ReplaceAll(dump, "\r\n", "\n"), "\n") {
+		if line != "" {
+			snapshot = append(snapshot, line)
+		}
+	}
+	slices.Sort(snapshot)
+	return snapshot
+}
@@ -0,0 +1,52 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSchemaDump(t *testing.T) {
+	tc := New(t, WithMigrationsFS(embeddedMigrations, "testdata/migrations"))
+
+	dump, err := tc.SchemaDump(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to dump schema: %v", err)
+	}
+
+	for _, want := range []string{
+		"table public.widgets\n",
+		"column public.widgets.name text not null\n",
+		"constraint public.widgets.widgets_pkey PRIMARY KEY (id)\n",
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("Expected dump to contain %q, got:\n%s", want, dump)
+		}
+	}
+	if strings.Contains(dump, "schema_migrations") || strings.Contains(dump, "spatial_ref_sys") {
+		t.Errorf("Expected migrations and extension tables to be left out, got:\n%s", dump)
+	}
+}
+
+func TestAssertSchemaGolden(t *testing.T) {
+	tc := New(t, WithMigrationsFS(embeddedMigrations, "testdata/migrations"))
+
+	t.Chdir(t.TempDir())
+	t.Setenv(UpdateGoldenEnv, "1")
+	tc.AssertSchemaGolden(t, "widgets.schema")
+
+	golden, err := os.ReadFile(filepath.Join("testdata", "widgets.schema"))
+	if err != nil {
+		t.Fatalf("Expected golden file to be written: %v", err)
+	}
+	if !strings.Contains(string(golden), "table public.widgets") {
+		t.Errorf("Expected golden file to describe widgets, got:\n%s", golden)
+	}
+
+	t.Setenv(UpdateGoldenEnv, "")
+	tc.AssertSchemaGolden(t, "widgets.schema")
+}
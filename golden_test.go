@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"flag"
+	"slices"
+	"testing"
+)
+
+func TestUpdateGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		flag  string // Value of -update, or "" when the test binary does not define it
+		value string
+		want  bool
+	}{
+		{name: "nothing set", want: false},
+		{name: "env 1", value: "1", want: true},
+		{name: "env true", value: "true", want: true},
+		{name: "env 0", value: "0", want: false},
+		{name: "env yes", value: "yes", want: false},
+		{name: "flag set", flag: "true", want: true},
+		{name: "flag unset", flag: "false", want: false},
+		{name: "flag unset with env", flag: "false", value: "1", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(UpdateGoldenEnv, tt.value)
+
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			if tt.flag != "" {
+				flags.Bool(UpdateGoldenFlag, false, "update golden files")
+				if err := flags.Parse([]string{"-" + UpdateGoldenFlag + "=" + tt.flag}); err != nil {
+					t.Fatalf("Failed to parse flags: %v", err)
+				}
+			}
+
+			if got := updateGoldenIn(flags); got != tt.want {
+				t.Errorf("Expected updateGolden to be %v with -update=%q and %s=%q, got %v", tt.want, tt.flag, UpdateGoldenEnv, tt.value, got)
+			}
+		})
+	}
+}
+
+func TestUpdateGolden_NoFlag(t *testing.T) {
+	// Consumers define the -update flag themselves; the package must not register one
+	if flag.Lookup(UpdateGoldenFlag) != nil {
+		t.Error("Expected the package not to register an -update flag")
+	}
+}
+
+func TestParseSchemaDump(t *testing.T) {
+	dump := "table public.users\r\ncolumn public.users.id integer not null\r\n\r\n"
+
+	got := parseSchemaDump(dump)
+	want := schemaSnapshot{"column public.users.id integer not null", "table public.users"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if round := parseSchemaDump(want.String()); !slices.Equal(round, want) {
+		t.Errorf("Expected dump to round trip, got %v", round)
+	}
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ImageFamily selects which PostgreSQL distribution the container runs
+type ImageFamily string
+
+const (
+	// ImageFamilyPostgres is the official postgres image without extensions
+	ImageFamilyPostgres ImageFamily = "postgres"
+	// ImageFamilyPostGIS is postgis/postgis, PostgreSQL with the PostGIS extensions
+	ImageFamilyPostGIS ImageFamily = "postgis"
+	// ImageFamilyPGVector is pgvector/pgvector, PostgreSQL with the vector extension
+	ImageFamilyPGVector ImageFamily = "pgvector"
+	// ImageFamilyTimescaleDB is timescale/timescaledb, PostgreSQL with TimescaleDB
+	ImageFamilyTimescaleDB ImageFamily = "timescaledb"
+	// ImageFamilyCustom runs the image given in PostgreSQLConfig.Image and makes no assumptions about its contents
+	ImageFamilyCustom ImageFamily = "custom"
+)
+
+// imageFamilyInfo describes how to build the image reference for a family
+type imageFamilyInfo struct {
+	repository     string
+	defaultVersion string
+	// extensionTables are tables the family's extensions create in the public schema
+	extensionTables []string
+}
+
+var imageFamilies = map[ImageFamily]imageFamilyInfo{
+	ImageFamilyPostgres: {
+		repository:     "postgres",
+		defaultVersion: "16-alpine",
+	},
+	ImageFamilyPostGIS: {
+		repository:     "postgis/postgis",
+		defaultVersion: "16-3.4",
+		extensionTables: []string{
+			"spatial_ref_sys",
+			"geometry_columns",
+			"geography_columns",
+		},
+	},
+	ImageFamilyPGVector: {
+		repository:     "pgvector/pgvector",
+		defaultVersion: "pg16",
+	},
+	ImageFamilyTimescaleDB: {
+		repository:     "timescale/timescaledb",
+		defaultVersion: "latest-pg16",
+	},
+	ImageFamilyCustom: {},
+}
+
+// imageFamily returns the configured family, defaulting to PostGIS
+func (c *PostgreSQLConfig) imageFamily() ImageFamily {
+	if c == nil || c.ImageFamily == "" {
+		return ImageFamilyPostGIS
+	}
+	return c.ImageFamily
+}
+
+// imageReference returns the image the container is started from.
+// Image takes precedence; otherwise the reference is built from the family and PostgreSQLVersion,
+// or the family's default tag when no version is set.
+func (c *PostgreSQLConfig) imageReference() string {
+	if c.Image != "" {
+		return c.Image
+	}
+
+	family := c.imageFamily()
+	info, ok := imageFamilies[family]
+	if !ok || info.repository == "" {
+		return ""
+	}
+
+	version := c.PostgreSQLVersion
+	if version == "" {
+		version = info.defaultVersion
+	}
+	return fmt.Sprintf("%s:%s", info.repository, version)
+}
+
+// extensionTables returns the tables created by the family's extensions
+func (f ImageFamily) extensionTables() []string {
+	return imageFamilies[f].extensionTables
+}
+
+// validateImageFamily checks that family is one of the known families
+func validateImageFamily(family ImageFamily) error {
+	if _, ok := imageFamilies[family]; !ok {
+		return &ConfigError{Field: "ImageFamily", Value: string(family), Reason: "is not a known image family"}
+	}
+	return nil
+}
+
+// inferImageFamily guesses the family of a full image reference from its repository name
+func inferImageFamily(image string) ImageFamily {
+	repository := image
+	if i := strings.LastIndex(repository, "@"); i >= 0 {
+		repository = repository[:i]
+	}
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository = repository[:i]
+	}
+	name := repository[strings.LastIndex(repository, "/")+1:]
+
+	switch {
+	case strings.Contains(name, "postgis"):
+		return ImageFamilyPostGIS
+	case strings.Contains(name, "pgvector"):
+		return ImageFamilyPGVector
+	case strings.Contains(name, "timescaledb"):
+		return ImageFamilyTimescaleDB
+	case name == "postgres":
+		return ImageFamilyPostgres
+	default:
+		return ImageFamilyCustom
+	}
+}
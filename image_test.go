@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestPostgreSQLConfig_ImageReference(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PostgreSQLConfig
+		want   string
+	}{
+		{
+			name:   "default family is postgis",
+			config: PostgreSQLConfig{PostgreSQLVersion: "16-3.4"},
+			want:   "postgis/postgis:16-3.4",
+		},
+		{
+			name:   "plain postgres",
+			config: PostgreSQLConfig{ImageFamily: ImageFamilyPostgres, PostgreSQLVersion: "17-alpine"},
+			want:   "postgres:17-alpine",
+		},
+		{
+			name:   "family default version",
+			config: PostgreSQLConfig{ImageFamily: ImageFamilyPGVector},
+			want:   "pgvector/pgvector:pg16",
+		},
+		{
+			name:   "default family without version",
+			config: PostgreSQLConfig{},
+			want:   "postgis/postgis:16-3.4",
+		},
+		{
+			name:   "explicit tag is kept on any family",
+			config: PostgreSQLConfig{ImageFamily: ImageFamilyPostgres, PostgreSQLVersion: "16-3.4"},
+			want:   "postgres:16-3.4",
+		},
+		{
+			name:   "timescaledb",
+			config: PostgreSQLConfig{ImageFamily: ImageFamilyTimescaleDB, PostgreSQLVersion: "2.17.2-pg16"},
+			want:   "timescale/timescaledb:2.17.2-pg16",
+		},
+		{
+			name:   "explicit image wins",
+			config: PostgreSQLConfig{ImageFamily: ImageFamilyPostGIS, PostgreSQLVersion: "16-3.4", Image: "registry.local/pg:1"},
+			want:   "registry.local/pg:1",
+		},
+		{
+			name:   "custom without image",
+			config: PostgreSQLConfig{ImageFamily: ImageFamilyCustom},
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.imageReference(); got != tt.want {
+				t.Errorf("Expected image reference to be %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDefaultPostgreSQLConfig_OtherFamily(t *testing.T) {
+	// The defaults leave the version to the family, so setting only the family picks its tag
+	for family, want := range map[ImageFamily]string{
+		ImageFamilyPostGIS:     "postgis/postgis:16-3.4",
+		ImageFamilyPostgres:    "postgres:16-alpine",
+		ImageFamilyTimescaleDB: "timescale/timescaledb:latest-pg16",
+	} {
+		config := DefaultPostgreSQLConfig()
+		config.ImageFamily = family
+
+		if got := config.imageReference(); got != want {
+			t.Errorf("Expected image reference for %s to be %s, got %s", family, want, got)
+		}
+	}
+}
+
+func TestImageFamily_ExtensionTables(t *testing.T) {
+	if !slices.Contains(ImageFamilyPostGIS.extensionTables(), "spatial_ref_sys") {
+		t.Error("Expected postgis family to preserve spatial_ref_sys")
+	}
+	for _, family := range []ImageFamily{ImageFamilyPostgres, ImageFamilyPGVector, ImageFamilyTimescaleDB, ImageFamilyCustom} {
+		if tables := family.extensionTables(); len(tables) != 0 {
+			t.Errorf("Expected %s family to preserve no extension tables, got %v", family, tables)
+		}
+	}
+}
+
+func TestInferImageFamily(t *testing.T) {
+	tests := []struct {
+		image string
+		want  ImageFamily
+	}{
+		{image: "postgres:17-alpine", want: ImageFamilyPostgres},
+		{image: "docker.io/library/postgres:16", want: ImageFamilyPostgres},
+		{image: "postgis/postgis:16-3.4", want: ImageFamilyPostGIS},
+		{image: "pgvector/pgvector:pg16", want: ImageFamilyPGVector},
+		{image: "timescale/timescaledb-ha:pg16", want: ImageFamilyTimescaleDB},
+		{image: "localhost:5000/postgres", want: ImageFamilyPostgres},
+		{image: "ghcr.io/acme/db:1@sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", want: ImageFamilyCustom},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := inferImageFamily(tt.image); got != tt.want {
+				t.Errorf("Expected family of %s to be %s, got %s", tt.image, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWithImageFamily(t *testing.T) {
+	config, err := buildConfig(WithImageFamily(ImageFamilyPostgres, "17-alpine"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := config.imageReference(); got != "postgres:17-alpine" {
+		t.Errorf("Expected image reference to be postgres:17-alpine, got %s", got)
+	}
+
+	config, err = buildConfig(WithImage("registry.local/team/pg:16"), WithImageFamily(ImageFamilyPostGIS, "ignored"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.imageReference() != "registry.local/team/pg:16" {
+		t.Errorf("Expected explicit image to be kept, got %s", config.imageReference())
+	}
+	if config.ImageFamily != ImageFamilyPostGIS {
+		t.Errorf("Expected ImageFamily to be postgis, got %s", config.ImageFamily)
+	}
+
+	if _, err := buildConfig(WithImageFamily("oracle", "")); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for unknown family, got %v", err)
+	}
+	if _, err := buildConfig(WithImageFamily(ImageFamilyCustom, "")); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for custom family without image, got %v", err)
+	}
+}
+
+func TestValidate_CustomFamilyRequiresImage(t *testing.T) {
+	config := DefaultPostgreSQLConfig()
+	config.ImageFamily = ImageFamilyCustom
+
+	var cfgErr *ConfigError
+	if err := config.Validate(); !errors.As(err, &cfgErr) || cfgErr.Field != "Image" {
+		t.Errorf("Expected ConfigError for Image, got %v", err)
+	}
+
+	config.Image = "registry.local/pg:16"
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
@@ -278,10 +278,11 @@ func TestNewTestDatabase(t *testing.T) {
 	defer tc.Close()
 
 	// Create a new database
-	newDBURL, err := tc.NewTestDatabase("test_new_db")
+	db, err := tc.NewTestDatabase("test_new_db")
 	if err != nil {
 		t.Fatalf("Failed to create new test database: %v", err)
 	}
+	newDBURL := db.URL
 
 	if newDBURL == "" {
 		t.Error("Expected non-empty database URL")
@@ -298,6 +299,10 @@ func TestNewTestDatabase(t *testing.T) {
 	if !strings.Contains(newDBURL, "test_new_db") {
 		t.Error("New database URL should contain the database name")
 	}
+
+	if err := db.Drop(ctx); err != nil {
+		t.Errorf("Failed to drop test database: %v", err)
+	}
 }
 
 func TestStartPostgreSQLContainerWithMigrations(t *testing.T) {
@@ -0,0 +1,18 @@
+//go:build unix
+
+package postgres
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive, blocking lock on f that is released if the process exits
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile releases a lock taken with lockFile
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,38 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"embed"
+	"testing"
+)
+
+//go:embed testdata/migrations/*.sql
+var embeddedMigrations embed.FS
+
+func TestMigrationsFS(t *testing.T) {
+	tc := New(t, WithMigrationsFS(embeddedMigrations, "testdata/migrations"))
+
+	var exists bool
+	err := tc.Pool.QueryRow(context.Background(), "SELECT to_regclass('widgets') IS NOT NULL").Scan(&exists)
+	if err != nil {
+		t.Fatalf("Failed to check widgets table: %v", err)
+	}
+	if !exists {
+		t.Error("Expected widgets table to be created from the embedded migrations")
+	}
+}
+
+func TestMigrationsFS_Template(t *testing.T) {
+	tc := New(t, WithMigrationsFS(embeddedMigrations, "testdata/migrations"))
+
+	if err := tc.PrepareTemplate(context.Background(), ""); err != nil {
+		t.Fatalf("Failed to prepare template: %v", err)
+	}
+
+	db := tc.CloneDatabase(t)
+	if _, err := db.Pool.Exec(context.Background(), "INSERT INTO widgets (name) VALUES ('sprocket')"); err != nil {
+		t.Errorf("Expected cloned database to have widgets table: %v", err)
+	}
+}
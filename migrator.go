@@ -0,0 +1,226 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+)
+
+// Migrator applies migrations to a newly created database.
+// Implement it to plug in a migration tool the package does not support out of the box.
+type Migrator interface {
+	// Migrate brings the database up to date. databaseURL and pool both point at that database.
+	Migrate(ctx context.Context, databaseURL string, pool *pgxpool.Pool) error
+
+	// MigrationsTable is the table the migrator records applied migrations in.
+	// CleanAllTables never truncates it.
+	MigrationsTable() string
+}
+
+const (
+	golangMigrateTable = "schema_migrations"
+	gooseTable         = "goose_db_version"
+	sqlFilesTable      = "sql_migrations"
+)
+
+// migrator returns the configured Migrator, defaulting to golang-migrate on the configured source.
+// A golang-migrate Migrator without a logger reports warnings through the configuration's.
+func (c *PostgreSQLConfig) migrator() Migrator {
+	if c != nil && c.Migrator != nil {
+		if m, ok := c.Migrator.(*golangMigrator); ok && m.logf == nil {
+			return &golangMigrator{source: m.source, logf: c.logf}
+		}
+		return c.Migrator
+	}
+	if c == nil {
+		return &golangMigrator{}
+	}
+	return &golangMigrator{source: c.migrationSource(), logf: c.logf}
+}
+
+// migrationsTable returns the table of the active migrator
+func (tc *PostgreSQLTestContainer) migrationsTable() string {
+	return tc.config.migrator().MigrationsTable()
+}
+
+// golangMigrator runs golang-migrate migrations
+type golangMigrator struct {
+	source migrationSource
+	logf   func(format string, args ...any)
+}
+
+// NewGolangMigrator returns a Migrator running golang-migrate migrations from a host path.
+// An empty path uses FindMigrationsPath.
+func NewGolangMigrator(migrationsPath string) Migrator {
+	return &golangMigrator{source: migrationSource{path: migrationsPath}}
+}
+
+// NewGolangMigratorFS returns a Migrator running golang-migrate migrations from dir in fsys
+func NewGolangMigratorFS(fsys fs.FS, dir string) Migrator {
+	if dir == "" {
+		dir = "."
+	}
+	return &golangMigrator{source: migrationSource{fsys: fsys, dir: dir}}
+}
+
+func (m *golangMigrator) Migrate(ctx context.Context, databaseURL string, pool *pgxpool.Pool) error {
+	return runMigrations(databaseURL, m.source, m.logger())
+}
+
+// logger returns the function warnings go to, defaulting to stdout
+func (m *golangMigrator) logger() func(format string, args ...any) {
+	if m.logf == nil {
+		return (*PostgreSQLConfig)(nil).logf
+	}
+	return m.logf
+}
+
+func (m *golangMigrator) MigrationsTable() string {
+	return golangMigrateTable
+}
+
+// gooseMigrator runs goose migrations through goose's Provider, so the files and the
+// version table are exactly what the goose CLI reads and writes
+type gooseMigrator struct {
+	fsys fs.FS
+	dir  string
+	opts []goose.ProviderOption
+}
+
+// NewGooseMigrator returns a Migrator running the goose migrations in dir of fsys.
+// A nil fsys reads dir from the host. opts are passed to goose.NewProvider, for example
+// goose.WithGoMigrations to register Go migrations. Snapshots and shared databases
+// fingerprint the files in dir only.
+func NewGooseMigrator(fsys fs.FS, dir string, opts ...goose.ProviderOption) Migrator {
+	if fsys == nil {
+		fsys, dir = os.DirFS(dir), "."
+	}
+	if dir == "" {
+		dir = "."
+	}
+	return &gooseMigrator{fsys: fsys, dir: dir, opts: opts}
+}
+
+func (m *gooseMigrator) MigrationsTable() string {
+	return gooseTable
+}
+
+func (m *gooseMigrator) Migrate(ctx context.Context, databaseURL string, pool *pgxpool.Pool) error {
+	// Closing the provider closes db, which hands its connections back to pool
+	provider, err := m.provider(stdlib.OpenDBFromPool(pool))
+	if errors.Is(err, goose.ErrNoMigrations) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer provider.Close()
+
+	if _, err := provider.Up(ctx); err != nil {
+		return fmt.Errorf("failed to run goose migrations: %w", err)
+	}
+
+	return nil
+}
+
+// provider opens a goose Provider on db for the migrations in m.dir
+func (m *gooseMigrator) provider(db *sql.DB) (*goose.Provider, error) {
+	fsys, err := fs.Sub(m.fsys, m.dir)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to read goose migrations in %s: %w", m.dir, err)
+	}
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, fsys, m.opts...)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load goose migrations in %s: %w", m.dir, err)
+	}
+
+	return provider, nil
+}
+
+// sqlFilesMigrator runs plain .sql files in lexical order, each once
+type sqlFilesMigrator struct {
+	fsys fs.FS
+	dir  string
+}
+
+// NewSQLFilesMigrator returns a Migrator that runs every .sql file in dir of fsys in lexical
+// order, skipping *.down.sql files. Each file runs in its own transaction and is recorded by
+// name, so files already applied are skipped. A nil fsys reads dir from the host.
+func NewSQLFilesMigrator(fsys fs.FS, dir string) Migrator {
+	if fsys == nil {
+		fsys, dir = os.DirFS(dir), "."
+	}
+	if dir == "" {
+		dir = "."
+	}
+	return &sqlFilesMigrator{fsys: fsys, dir: dir}
+}
+
+func (m *sqlFilesMigrator) MigrationsTable() string {
+	return sqlFilesTable
+}
+
+func (m *sqlFilesMigrator) Migrate(ctx context.Context, databaseURL string, pool *pgxpool.Pool) error {
+	files, err := resolveScripts(m.fsys, []string{path.Join(m.dir, "*.sql")})
+	if err != nil {
+		return err
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseConnFailed, err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS `+sqlFilesTable+` (
+			name TEXT PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`); err != nil {
+		return fmt.Errorf("failed to create %s: %w", sqlFilesTable, err)
+	}
+
+	for _, file := range files {
+		name := path.Base(file.name)
+		if strings.HasSuffix(name, ".down.sql") {
+			continue
+		}
+
+		var applied bool
+		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM "+sqlFilesTable+" WHERE name = $1)", name).Scan(&applied); err != nil {
+			return fmt.Errorf("failed to check migration %s: %w", name, err)
+		}
+		if applied {
+			continue
+		}
+
+		err := pgx.BeginFunc(ctx, conn.Conn(), func(tx pgx.Tx) error {
+			if err := runScripts(ctx, conn.Conn(), []scriptFile{file}); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, "INSERT INTO "+sqlFilesTable+" (name) VALUES ($1)", name); err != nil {
+				return fmt.Errorf("failed to record migration %s: %w", name, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
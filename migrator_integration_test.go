@@ -0,0 +1,90 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGooseMigrator(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"migrations/00001_create_users.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE users (id SERIAL PRIMARY KEY, name TEXT NOT NULL);
+
+-- +goose StatementBegin
+CREATE FUNCTION user_count() RETURNS bigint AS $$
+BEGIN
+  RETURN (SELECT count(*) FROM users);
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION user_count();
+DROP TABLE users;
+`)},
+		"migrations/00002_index_users.sql": {Data: []byte(`-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY users_name ON users (name);
+
+-- +goose Down
+DROP INDEX users_name;
+`)},
+	}
+
+	tc := New(t, WithMigrator(NewGooseMigrator(fsys, "migrations")))
+
+	var version int64
+	if err := tc.Pool.QueryRow(ctx, "SELECT max(version_id) FROM goose_db_version WHERE is_applied").Scan(&version); err != nil {
+		t.Fatalf("Failed to read goose version: %v", err)
+	}
+	if version != 2 {
+		t.Errorf("Expected goose version 2, got %d", version)
+	}
+
+	if _, err := tc.Pool.Exec(ctx, "INSERT INTO users (name) VALUES ('alice')"); err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	if err := tc.CleanAllTables(ctx); err != nil {
+		t.Fatalf("Failed to clean tables: %v", err)
+	}
+
+	var rows int
+	if err := tc.Pool.QueryRow(ctx, "SELECT count(*) FROM goose_db_version").Scan(&rows); err != nil {
+		t.Fatalf("Failed to count goose versions: %v", err)
+	}
+	if rows != 3 {
+		t.Errorf("Expected CleanAllTables to keep goose_db_version (3 rows), got %d", rows)
+	}
+}
+
+func TestSQLFilesMigrator(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"sql/001_schema.sql":      {Data: []byte("CREATE TABLE flags (name TEXT PRIMARY KEY, enabled BOOLEAN NOT NULL);")},
+		"sql/001_schema.down.sql": {Data: []byte("DROP TABLE flags;")},
+		"sql/002_flags.sql":       {Data: []byte("COPY flags FROM STDIN;\nbeta\tt\nlegacy\tf\n\\.\n")},
+	}
+	migrator := NewSQLFilesMigrator(fsys, "sql")
+
+	tc := New(t, WithMigrator(migrator))
+
+	// Running again must skip the files that were already applied
+	if err := migrator.Migrate(ctx, tc.DatabaseURL, tc.Pool); err != nil {
+		t.Fatalf("Expected second run to be a no-op, got %v", err)
+	}
+
+	var flags, applied int
+	if err := tc.Pool.QueryRow(ctx, "SELECT count(*) FROM flags").Scan(&flags); err != nil {
+		t.Fatalf("Failed to count flags: %v", err)
+	}
+	if err := tc.Pool.QueryRow(ctx, "SELECT count(*) FROM sql_migrations").Scan(&applied); err != nil {
+		t.Fatalf("Failed to count applied migrations: %v", err)
+	}
+	if flags != 2 || applied != 2 {
+		t.Errorf("Expected 2 flags and 2 applied files, got %d and %d", flags, applied)
+	}
+}
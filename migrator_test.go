@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+)
+
+func TestPostgreSQLConfig_Migrator(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *PostgreSQLConfig
+		wantTable string
+	}{
+		{name: "nil config", config: nil, wantTable: "schema_migrations"},
+		{name: "default", config: DefaultPostgreSQLConfig(), wantTable: "schema_migrations"},
+		{name: "goose", config: &PostgreSQLConfig{Migrator: NewGooseMigrator(fstest.MapFS{}, ".")}, wantTable: "goose_db_version"},
+		{name: "sql files", config: &PostgreSQLConfig{Migrator: NewSQLFilesMigrator(fstest.MapFS{}, ".")}, wantTable: "sql_migrations"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.migrator().MigrationsTable(); got != tt.wantTable {
+				t.Errorf("Expected migrations table to be %s, got %s", tt.wantTable, got)
+			}
+
+			tc := &PostgreSQLTestContainer{config: tt.config}
+			if got := tc.migrationsTable(); got != tt.wantTable {
+				t.Errorf("Expected container migrations table to be %s, got %s", tt.wantTable, got)
+			}
+		})
+	}
+}
+
+func TestWithMigrator(t *testing.T) {
+	migrator := NewSQLFilesMigrator(fstest.MapFS{}, "sql")
+	config, err := buildConfig(WithMigrator(migrator))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !config.RunMigrations || config.Migrator != migrator {
+		t.Error("Expected WithMigrator to enable migrations with the given migrator")
+	}
+
+	if _, err := buildConfig(WithMigrator(nil)); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for nil migrator, got %v", err)
+	}
+}
+
+func TestPostgreSQLConfig_MigratorLogger(t *testing.T) {
+	var logged int
+	config := &PostgreSQLConfig{
+		Migrator: NewGolangMigrator("migrations"),
+		Logf:     func(string, ...any) { logged++ },
+	}
+
+	m, ok := config.migrator().(*golangMigrator)
+	if !ok {
+		t.Fatalf("Expected a golang-migrate migrator, got %T", config.migrator())
+	}
+	m.logger()("warning")
+	if logged != 1 {
+		t.Error("Expected a golang-migrate migrator without a logger to use the configuration's Logf")
+	}
+	if config.Migrator.(*golangMigrator).logf != nil {
+		t.Error("Expected the configured migrator to be left unchanged")
+	}
+}
+
+// offlineDB returns a *sql.DB that never connects, for loading goose providers
+func offlineDB(t *testing.T) *sql.DB {
+	t.Helper()
+	config, err := pgx.ParseConfig("postgres://localhost/offline")
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	return sql.OpenDB(stdlib.GetConnector(*config))
+}
+
+func TestGooseMigrator_Provider(t *testing.T) {
+	up := func(sql string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte("-- +goose Up\n" + sql)}
+	}
+
+	m := NewGooseMigrator(fstest.MapFS{
+		"migrations/00010_b.sql":  up("SELECT 10;"),
+		"migrations/00002_a.sql":  up("SELECT 2;"),
+		"migrations/README.md":    {Data: []byte("docs")},
+		"migrations/fixtures/x.y": {Data: []byte("ignored")},
+	}, "migrations").(*gooseMigrator)
+
+	provider, err := m.provider(offlineDB(t))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer provider.Close()
+
+	sources := provider.ListSources()
+	if len(sources) != 2 || sources[0].Version != 2 || sources[1].Version != 10 {
+		t.Errorf("Expected versions 2 and 10 in order, got %+v", sources)
+	}
+
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want error
+	}{
+		{name: "no migrations", fsys: fstest.MapFS{"README.md": {Data: []byte("docs")}}, want: goose.ErrNoMigrations},
+		{name: "duplicate version", fsys: fstest.MapFS{"001_a.sql": up("SELECT 1;"), "1_b.sql": up("SELECT 1;")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewGooseMigrator(tt.fsys, "").(*gooseMigrator).provider(offlineDB(t))
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
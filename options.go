@@ -0,0 +1,486 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Option customises the PostgreSQLConfig used by Start and New.
+// Options validate their input and return a *ConfigError for invalid values.
+type Option func(*PostgreSQLConfig) error
+
+// ConfigError reports an invalid configuration value
+type ConfigError struct {
+	Field  string
+	Value  any
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	value := fmt.Sprint(e.Value)
+	if s, ok := e.Value.(string); ok {
+		value = fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%v: %s %s (got %s)", ErrInvalidConfig, e.Field, e.Reason, value)
+}
+
+// Is reports whether target is ErrInvalidConfig
+func (e *ConfigError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+// PoolConfig holds the connection pool settings applied by WithPoolConfig
+type PoolConfig struct {
+	MaxConns    int32
+	MinConns    int32
+	MaxConnLife time.Duration
+	MaxConnIdle time.Duration
+}
+
+// imageReferencePattern loosely matches a Docker image reference: [registry/]name[:tag][@digest]
+var imageReferencePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._\-/:]*(@sha256:[a-f0-9]{64})?$`)
+
+// Start creates and starts a PostgreSQL container from DefaultPostgreSQLConfig with opts applied.
+// Invalid options are reported before any container is started.
+func Start(ctx context.Context, opts ...Option) (*PostgreSQLTestContainer, error) {
+	config, err := buildConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return StartPostgreSQLContainerWithCheck(ctx, config)
+}
+
+// buildConfig layers opts over DefaultPostgreSQLConfig and validates the result
+func buildConfig(opts ...Option) (*PostgreSQLConfig, error) {
+	config := DefaultPostgreSQLConfig()
+	for _, opt := range opts {
+		if err := opt(config); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Validate checks the configuration for values that would fail at startup
+func (c *PostgreSQLConfig) Validate() error {
+	var errs []error
+
+	if err := validateIdentifier("DatabaseName", c.DatabaseName); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Username == "" {
+		errs = append(errs, &ConfigError{Field: "Username", Value: c.Username, Reason: "must not be empty"})
+	}
+	if c.Password == "" {
+		errs = append(errs, &ConfigError{Field: "Password", Value: "", Reason: "must not be empty"})
+	}
+	if c.ImageFamily != "" {
+		if err := validateImageFamily(c.ImageFamily); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.imageFamily() == ImageFamilyCustom && c.Image == "" {
+		errs = append(errs, &ConfigError{Field: "Image", Value: "", Reason: "is required for the custom image family"})
+	} else if !imageReferencePattern.MatchString(c.imageReference()) {
+		errs = append(errs, &ConfigError{Field: "Image", Value: c.imageReference(), Reason: "is not a valid image reference"})
+	}
+	if err := validatePool(PoolConfig{MaxConns: c.MaxConns, MinConns: c.MinConns, MaxConnLife: c.MaxConnLife, MaxConnIdle: c.MaxConnIdle}); err != nil {
+		errs = append(errs, err)
+	}
+	if c.CloneMaxConns < 0 {
+		errs = append(errs, &ConfigError{Field: "CloneMaxConns", Value: c.CloneMaxConns, Reason: "must not be negative"})
+	}
+	if c.StartupTimeout <= 0 {
+		errs = append(errs, &ConfigError{Field: "StartupTimeout", Value: c.StartupTimeout, Reason: "must be positive"})
+	}
+	if err := validateProfile(c.PerformanceProfile); err != nil {
+		errs = append(errs, err)
+	}
+	if c.PerformanceProfile == ProfileCustom && len(c.ServerSettings) == 0 {
+		errs = append(errs, &ConfigError{Field: "ServerSettings", Value: c.ServerSettings, Reason: "must not be empty for the custom profile"})
+	}
+	if err := validateSettingNames(c.ServerSettings); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateScriptPatterns("InitScripts", c.InitScripts); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateScriptPatterns("SeedScripts", c.SeedScripts); err != nil {
+		errs = append(errs, err)
+	}
+	for _, ext := range c.Extensions {
+		if err := validateExtension(ext); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := validateCleanOptions(c.Cleaning); err != nil {
+		errs = append(errs, err)
+	}
+	if c.ExternalDatabaseURL != "" {
+		if err := validateExternalDatabaseURL(c.ExternalDatabaseURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.SnapshotCache {
+		if c.ReuseContainer {
+			errs = append(errs, &ConfigError{Field: "SnapshotCache", Value: true, Reason: "cannot be combined with ReuseContainer"})
+		}
+		if _, ok := c.Migrator.(snapshotDigester); c.RunMigrations && c.Migrator != nil && !ok {
+			errs = append(errs, &ConfigError{Field: "Migrator", Value: fmt.Sprintf("%T", c.Migrator), Reason: "does not support SnapshotCache"})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WithConfig starts from a copy of config instead of DefaultPostgreSQLConfig.
+// Slices and maps are copied too, so later options never modify config.
+func WithConfig(config *PostgreSQLConfig) Option {
+	return func(c *PostgreSQLConfig) error {
+		if config == nil {
+			return nil
+		}
+
+		*c = *config
+		c.ServerSettings = maps.Clone(config.ServerSettings)
+		c.Extensions = slices.Clone(config.Extensions)
+		c.InitScripts = slices.Clone(config.InitScripts)
+		c.SeedScripts = slices.Clone(config.SeedScripts)
+		c.Cleaning.Schemas = slices.Clone(config.Cleaning.Schemas)
+		c.Cleaning.ExcludeTables = slices.Clone(config.Cleaning.ExcludeTables)
+		c.Cleaning.ExcludePatterns = slices.Clone(config.Cleaning.ExcludePatterns)
+		c.Cleaning.PreserveTables = slices.Clone(config.Cleaning.PreserveTables)
+		return nil
+	}
+}
+
+// WithImage starts the container from a full image reference, e.g. "postgres:17-alpine".
+// The image family is inferred from the repository name; images that are not recognised
+// use ImageFamilyCustom. Use WithImageFamily afterwards to override the inferred family.
+func WithImage(image string) Option {
+	return func(c *PostgreSQLConfig) error {
+		if !imageReferencePattern.MatchString(image) {
+			return &ConfigError{Field: "Image", Value: image, Reason: "is not a valid image reference"}
+		}
+		c.Image = image
+		c.ImageFamily = inferImageFamily(image)
+		return nil
+	}
+}
+
+// WithImageFamily selects the image family and the tag within it.
+// An empty version uses the family's default tag. When an explicit image was set with
+// WithImage, only the family (which decides the tables CleanAllTables preserves) changes.
+func WithImageFamily(family ImageFamily, version string) Option {
+	return func(c *PostgreSQLConfig) error {
+		if err := validateImageFamily(family); err != nil {
+			return err
+		}
+		if family == ImageFamilyCustom && c.Image == "" {
+			return &ConfigError{Field: "ImageFamily", Value: string(family), Reason: "requires WithImage"}
+		}
+		c.ImageFamily = family
+		if c.Image == "" {
+			c.PostgreSQLVersion = version
+		}
+		return nil
+	}
+}
+
+// WithDatabase sets the name of the database created in the container
+func WithDatabase(name string) Option {
+	return func(c *PostgreSQLConfig) error {
+		if err := validateIdentifier("DatabaseName", name); err != nil {
+			return err
+		}
+		c.DatabaseName = name
+		return nil
+	}
+}
+
+// WithCredentials sets the superuser name and password
+func WithCredentials(username, password string) Option {
+	return func(c *PostgreSQLConfig) error {
+		if username == "" {
+			return &ConfigError{Field: "Username", Value: username, Reason: "must not be empty"}
+		}
+		if password == "" {
+			return &ConfigError{Field: "Password", Value: "", Reason: "must not be empty"}
+		}
+		c.Username = username
+		c.Password = password
+		return nil
+	}
+}
+
+// WithMigrations runs migrations from path on startup.
+// An empty path uses FindMigrationsPath; a non-empty path must be an existing directory.
+func WithMigrations(path string) Option {
+	return func(c *PostgreSQLConfig) error {
+		if path != "" {
+			info, err := os.Stat(path)
+			if err != nil {
+				return &ConfigError{Field: "MigrationsPath", Value: path, Reason: err.Error()}
+			}
+			if !info.IsDir() {
+				return &ConfigError{Field: "MigrationsPath", Value: path, Reason: "is not a directory"}
+			}
+		}
+		c.RunMigrations = true
+		c.MigrationsPath = path
+		return nil
+	}
+}
+
+// WithMigrationsFS runs the migrations in dir of fsys on startup, e.g. from an embed.FS.
+// An empty dir means the root of fsys.
+func WithMigrationsFS(fsys fs.FS, dir string) Option {
+	return func(c *PostgreSQLConfig) error {
+		if fsys == nil {
+			return &ConfigError{Field: "MigrationsFS", Value: fsys, Reason: "must not be nil"}
+		}
+		if dir == "" {
+			dir = "."
+		}
+		info, err := fs.Stat(fsys, dir)
+		if err != nil {
+			return &ConfigError{Field: "MigrationsDir", Value: dir, Reason: err.Error()}
+		}
+		if !info.IsDir() {
+			return &ConfigError{Field: "MigrationsDir", Value: dir, Reason: "is not a directory"}
+		}
+		c.RunMigrations = true
+		c.MigrationsFS = fsys
+		c.MigrationsDir = dir
+		return nil
+	}
+}
+
+// WithMigrator runs migrations on startup with migrator instead of golang-migrate
+func WithMigrator(migrator Migrator) Option {
+	return func(c *PostgreSQLConfig) error {
+		if migrator == nil {
+			return &ConfigError{Field: "Migrator", Value: migrator, Reason: "must not be nil"}
+		}
+		c.RunMigrations = true
+		c.Migrator = migrator
+		return nil
+	}
+}
+
+// WithPoolConfig sets every connection pool setting, so a zero MinConns is always deliberate
+func WithPoolConfig(pool PoolConfig) Option {
+	return func(c *PostgreSQLConfig) error {
+		if err := validatePool(pool); err != nil {
+			return err
+		}
+		c.MaxConns = pool.MaxConns
+		c.MinConns = pool.MinConns
+		c.MaxConnLife = pool.MaxConnLife
+		c.MaxConnIdle = pool.MaxConnIdle
+		return nil
+	}
+}
+
+// WithClonePoolSize caps the connection pool of each database created by CloneDatabase
+func WithClonePoolSize(maxConns int32) Option {
+	return func(c *PostgreSQLConfig) error {
+		if maxConns < 1 {
+			return &ConfigError{Field: "CloneMaxConns", Value: maxConns, Reason: "must be at least 1"}
+		}
+		c.CloneMaxConns = maxConns
+		return nil
+	}
+}
+
+// WithStartupTimeout sets how long to wait for the container to become ready
+func WithStartupTimeout(timeout time.Duration) Option {
+	return func(c *PostgreSQLConfig) error {
+		if timeout <= 0 {
+			return &ConfigError{Field: "StartupTimeout", Value: timeout, Reason: "must be positive"}
+		}
+		c.StartupTimeout = timeout
+		return nil
+	}
+}
+
+// WithPerformanceProfile starts the server with the given profile's settings
+func WithPerformanceProfile(profile PerformanceProfile) Option {
+	return func(c *PostgreSQLConfig) error {
+		if err := validateProfile(profile); err != nil {
+			return err
+		}
+		c.PerformanceProfile = profile
+		return nil
+	}
+}
+
+// WithServerSettings adds server settings passed as -c flags, overriding those of the profile
+func WithServerSettings(settings map[string]string) Option {
+	return func(c *PostgreSQLConfig) error {
+		if err := validateSettingNames(settings); err != nil {
+			return err
+		}
+		// Copy so the caller's map (or one shared through WithConfig) is never modified
+		merged := make(map[string]string, len(c.ServerSettings)+len(settings))
+		maps.Copy(merged, c.ServerSettings)
+		maps.Copy(merged, settings)
+		c.ServerSettings = merged
+		return nil
+	}
+}
+
+// WithExtensions installs extensions after the container starts and before migrations run
+func WithExtensions(extensions ...Extension) Option {
+	return func(c *PostgreSQLConfig) error {
+		for _, ext := range extensions {
+			if err := validateExtension(ext); err != nil {
+				return err
+			}
+		}
+		c.Extensions = append(c.Extensions, extensions...)
+		return nil
+	}
+}
+
+// WithInitScripts runs SQL files (paths or globs) after extensions are installed and before migrations
+func WithInitScripts(patterns ...string) Option {
+	return func(c *PostgreSQLConfig) error {
+		if err := validateScriptPatterns("InitScripts", patterns); err != nil {
+			return err
+		}
+		c.InitScripts = append(c.InitScripts, patterns...)
+		return nil
+	}
+}
+
+// WithSeedScripts runs SQL files (paths or globs) after migrations
+func WithSeedScripts(patterns ...string) Option {
+	return func(c *PostgreSQLConfig) error {
+		if err := validateScriptPatterns("SeedScripts", patterns); err != nil {
+			return err
+		}
+		c.SeedScripts = append(c.SeedScripts, patterns...)
+		return nil
+	}
+}
+
+// WithScriptsFS resolves init and seed scripts in fsys, e.g. an embed.FS
+func WithScriptsFS(fsys fs.FS) Option {
+	return func(c *PostgreSQLConfig) error {
+		c.ScriptsFS = fsys
+		return nil
+	}
+}
+
+// WithCleaning sets the defaults used by CleanAllTables, CleanSpecificTables and CleanTables
+func WithCleaning(options CleanOptions) Option {
+	return func(c *PostgreSQLConfig) error {
+		if err := validateCleanOptions(options); err != nil {
+			return err
+		}
+		c.Cleaning = options
+		return nil
+	}
+}
+
+// WithLogf routes warnings to logf
+func WithLogf(logf func(format string, args ...any)) Option {
+	return func(c *PostgreSQLConfig) error {
+		c.Logf = logf
+		return nil
+	}
+}
+
+// WithReuse shares one named container across test processes
+func WithReuse() Option {
+	return func(c *PostgreSQLConfig) error {
+		c.ReuseContainer = true
+		return nil
+	}
+}
+
+// WithSnapshotCache starts from a locally committed image of the prepared database.
+// The image is built on first use and rebuilt whenever the image, migrations, scripts or
+// extensions change; remove stale images with PruneSnapshotCache.
+func WithSnapshotCache() Option {
+	return func(c *PostgreSQLConfig) error {
+		c.SnapshotCache = true
+		return nil
+	}
+}
+
+// WithExternalDatabase creates the test database on an existing server instead of starting a container.
+// The URL must allow CREATE DATABASE; setting $TEST_DATABASE_URL has the same effect.
+func WithExternalDatabase(databaseURL string) Option {
+	return func(c *PostgreSQLConfig) error {
+		if err := validateExternalDatabaseURL(databaseURL); err != nil {
+			return err
+		}
+		c.ExternalDatabaseURL = databaseURL
+		return nil
+	}
+}
+
+// validateIdentifier checks a value used as a PostgreSQL identifier
+func validateIdentifier(field, name string) error {
+	if name == "" {
+		return &ConfigError{Field: field, Value: name, Reason: "must not be empty"}
+	}
+	if len(name) > maxIdentifierLength {
+		return &ConfigError{Field: field, Value: name, Reason: fmt.Sprintf("must be at most %d bytes", maxIdentifierLength)}
+	}
+	return nil
+}
+
+// validateExtension checks the identifiers of an extension
+func validateExtension(ext Extension) error {
+	if err := validateIdentifier("Extensions.Name", ext.Name); err != nil {
+		return err
+	}
+	if ext.Schema != "" {
+		return validateIdentifier("Extensions.Schema", ext.Schema)
+	}
+	return nil
+}
+
+// validateScriptPatterns checks the glob syntax of script patterns
+func validateScriptPatterns(field string, patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); pattern == "" || err != nil {
+			return &ConfigError{Field: field, Value: pattern, Reason: "is not a valid path or glob"}
+		}
+	}
+	return nil
+}
+
+// validatePool checks connection pool settings
+func validatePool(pool PoolConfig) error {
+	switch {
+	case pool.MaxConns < 1:
+		return &ConfigError{Field: "MaxConns", Value: pool.MaxConns, Reason: "must be at least 1"}
+	case pool.MinConns < 0:
+		return &ConfigError{Field: "MinConns", Value: pool.MinConns, Reason: "must not be negative"}
+	case pool.MinConns > pool.MaxConns:
+		return &ConfigError{Field: "MinConns", Value: pool.MinConns, Reason: fmt.Sprintf("must not exceed MaxConns (%d)", pool.MaxConns)}
+	case pool.MaxConnLife < 0:
+		return &ConfigError{Field: "MaxConnLife", Value: pool.MaxConnLife, Reason: "must not be negative"}
+	case pool.MaxConnIdle < 0:
+		return &ConfigError{Field: "MaxConnIdle", Value: pool.MaxConnIdle, Reason: "must not be negative"}
+	}
+	return nil
+}
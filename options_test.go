@@ -0,0 +1,263 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestWithConfig(t *testing.T) {
+	custom := DefaultPostgreSQLConfig()
+	custom.DatabaseName = "customdb"
+	custom.MaxConns = 20
+
+	config := DefaultPostgreSQLConfig()
+	if err := WithConfig(custom)(config); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.DatabaseName != "customdb" {
+		t.Errorf("Expected DatabaseName to be customdb, got %s", config.DatabaseName)
+	}
+	if config.MaxConns != 20 {
+		t.Errorf("Expected MaxConns to be 20, got %d", config.MaxConns)
+	}
+
+	// Modifying the result must not affect the source configuration
+	config.DatabaseName = "otherdb"
+	if custom.DatabaseName != "customdb" {
+		t.Errorf("Expected source config to be unchanged, got %s", custom.DatabaseName)
+	}
+}
+
+func TestWithConfig_CopiesSlicesAndMaps(t *testing.T) {
+	custom := DefaultPostgreSQLConfig()
+	custom.ServerSettings = map[string]string{"work_mem": "64MB"}
+	custom.Extensions = []Extension{{Name: "pg_trgm"}}
+	custom.InitScripts = []string{"init.sql"}
+	custom.SeedScripts = []string{"seed.sql"}
+	custom.Cleaning.Schemas = []string{"public"}
+	custom.Cleaning.ExcludeTables = []string{"audit_*"}
+	custom.Cleaning.ExcludePatterns = []*regexp.Regexp{regexp.MustCompile(`^public\.log_`)}
+	custom.Cleaning.PreserveTables = []string{"countries"}
+
+	config := DefaultPostgreSQLConfig()
+	if err := WithConfig(custom)(config); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	config.ServerSettings["work_mem"] = "128MB"
+	config.Extensions[0].Name = "postgis"
+	config.InitScripts[0] = "other.sql"
+	config.SeedScripts[0] = "other.sql"
+	config.Cleaning.Schemas[0] = "app"
+	config.Cleaning.ExcludeTables[0] = "tmp_*"
+	config.Cleaning.ExcludePatterns[0] = nil
+	config.Cleaning.PreserveTables[0] = "currencies"
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{name: "ServerSettings", got: custom.ServerSettings["work_mem"], want: "64MB"},
+		{name: "Extensions", got: custom.Extensions[0].Name, want: "pg_trgm"},
+		{name: "InitScripts", got: custom.InitScripts[0], want: "init.sql"},
+		{name: "SeedScripts", got: custom.SeedScripts[0], want: "seed.sql"},
+		{name: "Cleaning.Schemas", got: custom.Cleaning.Schemas[0], want: "public"},
+		{name: "Cleaning.ExcludeTables", got: custom.Cleaning.ExcludeTables[0], want: "audit_*"},
+		{name: "Cleaning.ExcludePatterns", got: custom.Cleaning.ExcludePatterns[0] != nil, want: true},
+		{name: "Cleaning.PreserveTables", got: custom.Cleaning.PreserveTables[0], want: "countries"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("Expected source %s to stay %v, got %v", tt.name, tt.want, tt.got)
+			}
+		})
+	}
+}
+
+func TestWithConfig_Nil(t *testing.T) {
+	config := DefaultPostgreSQLConfig()
+	if err := WithConfig(nil)(config); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.DatabaseName != "testdb" {
+		t.Errorf("Expected defaults to be kept, got DatabaseName %s", config.DatabaseName)
+	}
+}
+
+func TestOptions_Valid(t *testing.T) {
+	config, err := buildConfig(
+		WithImage("postgres:17-alpine"),
+		WithDatabase("appdb"),
+		WithCredentials("app", "secret"),
+		WithPoolConfig(PoolConfig{MaxConns: 4, MinConns: 0}),
+		WithClonePoolSize(3),
+		WithStartupTimeout(time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.imageReference() != "postgres:17-alpine" {
+		t.Errorf("Expected image to be postgres:17-alpine, got %s", config.imageReference())
+	}
+	if config.DatabaseName != "appdb" {
+		t.Errorf("Expected DatabaseName to be appdb, got %s", config.DatabaseName)
+	}
+	if config.Username != "app" || config.Password != "secret" {
+		t.Errorf("Expected credentials app/secret, got %s/%s", config.Username, config.Password)
+	}
+	if config.MaxConns != 4 {
+		t.Errorf("Expected MaxConns to be 4, got %d", config.MaxConns)
+	}
+	if config.MinConns != 0 {
+		t.Errorf("Expected MinConns to be 0, got %d", config.MinConns)
+	}
+	if config.CloneMaxConns != 3 {
+		t.Errorf("Expected CloneMaxConns to be 3, got %d", config.CloneMaxConns)
+	}
+	if config.StartupTimeout != time.Minute {
+		t.Errorf("Expected StartupTimeout to be 1m, got %v", config.StartupTimeout)
+	}
+}
+
+func TestOptions_Invalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		opt       Option
+		wantField string
+	}{
+		{name: "empty image", opt: WithImage(""), wantField: "Image"},
+		{name: "image with spaces", opt: WithImage("postgres 17"), wantField: "Image"},
+		{name: "empty database", opt: WithDatabase(""), wantField: "DatabaseName"},
+		{name: "long database", opt: WithDatabase(string(make([]byte, 64))), wantField: "DatabaseName"},
+		{name: "empty username", opt: WithCredentials("", "secret"), wantField: "Username"},
+		{name: "missing migrations", opt: WithMigrations("/does/not/exist"), wantField: "MigrationsPath"},
+		{name: "zero max conns", opt: WithPoolConfig(PoolConfig{MaxConns: 0}), wantField: "MaxConns"},
+		{name: "min exceeds max", opt: WithPoolConfig(PoolConfig{MaxConns: 2, MinConns: 3}), wantField: "MinConns"},
+		{name: "zero timeout", opt: WithStartupTimeout(0), wantField: "StartupTimeout"},
+		{name: "zero clone pool", opt: WithClonePoolSize(0), wantField: "CloneMaxConns"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildConfig(tt.opt)
+
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("Expected *ConfigError, got %v", err)
+			}
+			if configErr.Field != tt.wantField {
+				t.Errorf("Field = %v, want %v", configErr.Field, tt.wantField)
+			}
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Error("Expected error to match ErrInvalidConfig")
+			}
+		})
+	}
+}
+
+func TestWithMigrations(t *testing.T) {
+	dir := t.TempDir()
+
+	config, err := buildConfig(WithMigrations(dir))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.RunMigrations {
+		t.Error("Expected RunMigrations to be true")
+	}
+	if config.MigrationsPath != dir {
+		t.Errorf("Expected MigrationsPath to be %s, got %s", dir, config.MigrationsPath)
+	}
+}
+
+func TestValidate_WithConfig(t *testing.T) {
+	custom := DefaultPostgreSQLConfig()
+	custom.MinConns = 20 // Exceeds the default MaxConns
+
+	_, err := buildConfig(WithConfig(custom))
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestStart_InvalidOptionDoesNotStartContainer(t *testing.T) {
+	// An invalid option must fail before Docker is contacted
+	_, err := Start(context.Background(), WithDatabase(""))
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestConfigError_Error(t *testing.T) {
+	err := &ConfigError{Field: "DatabaseName", Value: "", Reason: "must not be empty"}
+
+	want := `invalid PostgreSQL configuration: DatabaseName must not be empty (got "")`
+	if err.Error() != want {
+		t.Errorf("Error message = %v, want %v", err.Error(), want)
+	}
+}
+
+func TestWithMigrationsFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"db/migrations/001_init.up.sql": {Data: []byte("CREATE TABLE a (id INT);")},
+	}
+
+	config, err := buildConfig(WithMigrationsFS(fsys, "db/migrations"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !config.RunMigrations {
+		t.Error("Expected RunMigrations to be true")
+	}
+	if source := config.migrationSource(); source.fsys == nil || source.dir != "db/migrations" {
+		t.Errorf("Expected fs source in db/migrations, got %+v", source)
+	}
+
+	config, err = buildConfig(WithMigrationsFS(fsys, ""))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.MigrationsDir != "." {
+		t.Errorf("Expected MigrationsDir to default to ., got %s", config.MigrationsDir)
+	}
+
+	tests := []struct {
+		name string
+		fsys fs.FS
+		dir  string
+	}{
+		{name: "nil fs", fsys: nil, dir: "db/migrations"},
+		{name: "missing dir", fsys: fsys, dir: "missing"},
+		{name: "file not dir", fsys: fsys, dir: "db/migrations/001_init.up.sql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := buildConfig(WithMigrationsFS(tt.fsys, tt.dir)); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+}
+
+func TestConfigFingerprint_MigrationsFS(t *testing.T) {
+	a := DefaultPostgreSQLConfig()
+	a.MigrationsFS = fstest.MapFS{"001_init.up.sql": {Data: []byte("CREATE TABLE a (id INT);")}}
+	b := DefaultPostgreSQLConfig()
+	b.MigrationsFS = fstest.MapFS{"001_init.up.sql": {Data: []byte("CREATE TABLE b (id INT);")}}
+
+	if configFingerprint(a) == configFingerprint(b) {
+		t.Error("Expected different embedded migrations to have different fingerprints")
+	}
+}
@@ -1,24 +1,28 @@
 // Package postgres provides PostgreSQL testcontainer utilities for Go tests.
 //
 // This package offers utilities for starting PostgreSQL containers in tests,
-// with support for PostGIS, automatic migration detection and running,
-// and cleanup helpers for test isolation.
+// with support for PostGIS and other image families, automatic migration
+// detection and running, and cleanup helpers for test isolation.
 package postgres
 
 import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
@@ -32,15 +36,14 @@ var (
 	ErrContainerPortConflict = errors.New("container port conflict detected")
 	ErrDatabaseConnFailed    = errors.New("failed to connect to container database")
 	ErrMigrationsFailed      = errors.New("database migrations failed")
+	ErrInvalidConfig         = errors.New("invalid PostgreSQL configuration")
+	ErrExtensionNotAvailable = errors.New("PostgreSQL extension not available")
+	ErrScriptFailed          = errors.New("SQL script failed")
+	ErrMigrationRoundTrip    = errors.New("migration round trip failed")
+	ErrNotDeferrable         = errors.New("foreign key in a reference cycle is not DEFERRABLE")
+	ErrPreservedReference    = errors.New("preserved table references a cleaned table")
 )
 
-// DockerAvailabilityResult holds information about Docker availability
-type DockerAvailabilityResult struct {
-	Available bool
-	Reason    string
-	Error     error
-}
-
 // PostgreSQLTestContainer holds the PostgreSQL test container and related resources
 type PostgreSQLTestContainer struct {
 	Container    *postgres.PostgresContainer
@@ -50,6 +53,16 @@ type PostgreSQLTestContainer struct {
 	DatabaseName string
 	Username     string
 	Password     string
+	Runtime      RuntimeInfo // Container runtime the container runs on; zero for an external server
+
+	config        *PostgreSQLConfig
+	adminDatabase string    // Database used to manage the package database of a reused container
+	packageLock   *pgx.Conn // Admin connection marking the package database of a reused container as in use
+	templateMu    sync.Mutex
+	templateName  string
+	cloneSeq      atomic.Uint64
+	databasesMu   sync.Mutex
+	databases     map[string]*TestDatabase // Databases created by NewTestDatabase and CloneDatabase
 }
 
 // PostgreSQLConfig provides configuration options for the PostgreSQL test container
@@ -60,7 +73,9 @@ type PostgreSQLConfig struct {
 	Password     string
 
 	// Image configuration
-	PostgreSQLVersion string // e.g., "16-3.4", "15-3.4" (version-postgis_version)
+	ImageFamily       ImageFamily // postgres, postgis, pgvector, timescaledb or custom (defaults to postgis)
+	PostgreSQLVersion string      // Image tag within the family, e.g., "16-3.4" for postgis or "17-alpine" for postgres; the family's default tag if empty
+	Image             string      // Full image reference; overrides the image built from ImageFamily and PostgreSQLVersion
 
 	// Connection configuration
 	MaxConns    int32
@@ -68,72 +83,69 @@ type PostgreSQLConfig struct {
 	MaxConnLife time.Duration
 	MaxConnIdle time.Duration
 
+	// CloneMaxConns caps the pool of each database created by CloneDatabase, which keeps no idle
+	// connections, so hundreds of parallel clones stay below max_connections (defaults to 2)
+	CloneMaxConns int32
+
 	// Container configuration
-	StartupTimeout time.Duration
+	StartupTimeout     time.Duration
+	PerformanceProfile PerformanceProfile // durable, fast or custom; empty keeps the default of fsync=off
+	ServerSettings     map[string]string  // Extra server settings passed as -c flags; override the profile
+
+	// Extension configuration
+	Extensions []Extension // Installed after the container starts and before migrations run
 
 	// Migration configuration
 	RunMigrations  bool
-	MigrationsPath string // Relative to the calling test file or absolute path
+	MigrationsPath string   // Relative to the calling test file or absolute path
+	MigrationsFS   fs.FS    // Migrations shipped in an fs.FS (e.g., embed.FS); takes precedence over MigrationsPath
+	MigrationsDir  string   // Subdirectory of MigrationsFS holding the migrations (defaults to ".")
+	Migrator       Migrator // Migration engine; defaults to golang-migrate on MigrationsFS or MigrationsPath
+
+	// Script configuration
+	InitScripts []string // SQL files or globs run before migrations
+	SeedScripts []string // SQL files or globs run after migrations
+	ScriptsFS   fs.FS    // Resolves InitScripts and SeedScripts when set; otherwise they are host paths
+
+	// Cleanup configuration
+	Cleaning CleanOptions // Defaults for CleanAllTables, CleanSpecificTables and CleanTables
+
+	// Reuse configuration
+	ReuseContainer bool // Share one named container across test processes; each package gets its own database
+	SnapshotCache  bool // Start from a locally committed image of the prepared database, building it on first use
+
+	// External server configuration
+	ExternalDatabaseURL string // Existing server to create the test database on instead of starting a container; defaults to $TEST_DATABASE_URL
+
+	// Logging configuration
+	Logf func(format string, args ...any) // Receives warnings; defaults to printing to stdout
 }
 
 // DefaultPostgreSQLConfig returns a sensible default configuration
 func DefaultPostgreSQLConfig() *PostgreSQLConfig {
 	return &PostgreSQLConfig{
-		DatabaseName:      "testdb",
-		Username:          "testuser",
-		Password:          "testpass",
-		PostgreSQLVersion: "16-3.4", // PostGIS 3.4 with PostgreSQL 16 (ARM64 compatible)
-		MaxConns:          10,
-		MinConns:          2,
-		MaxConnLife:       30 * time.Minute,
-		MaxConnIdle:       5 * time.Minute,
-		StartupTimeout:    30 * time.Second,
-		RunMigrations:     false, // Disabled by default for simple setup
-		MigrationsPath:    "",    // Will be auto-detected
+		DatabaseName:   "testdb",
+		Username:       "testuser",
+		Password:       "testpass",
+		ImageFamily:    ImageFamilyPostGIS,
+		MaxConns:       10,
+		MinConns:       2,
+		MaxConnLife:    30 * time.Minute,
+		MaxConnIdle:    5 * time.Minute,
+		CloneMaxConns:  defaultCloneMaxConns,
+		StartupTimeout: 30 * time.Second,
+		RunMigrations:  false, // Disabled by default for simple setup
+		MigrationsPath: "",    // Will be auto-detected
 	}
 }
 
-// CheckDockerAvailability checks if Docker is available and running
-func CheckDockerAvailability() DockerAvailabilityResult {
-	// Check if docker command exists
-	_, err := exec.LookPath("docker")
-	if err != nil {
-		return DockerAvailabilityResult{
-			Available: false,
-			Reason:    "Docker command not found in PATH",
-			Error:     err,
-		}
-	}
-
-	// Check if Docker daemon is running
-	cmd := exec.Command("docker", "info")
-	if err := cmd.Run(); err != nil {
-		return DockerAvailabilityResult{
-			Available: false,
-			Reason:    "Docker daemon is not running or accessible",
-			Error:     err,
-		}
-	}
-
-	// Check if we can pull images (basic functionality test)
-	cmd = exec.Command("docker", "images", "--format", "table")
-	if err := cmd.Run(); err != nil {
-		return DockerAvailabilityResult{
-			Available: false,
-			Reason:    "Docker is running but images command failed",
-			Error:     err,
-		}
-	}
-
-	return DockerAvailabilityResult{
-		Available: true,
-		Reason:    "Docker is available and running",
-		Error:     nil,
+// StartPostgreSQLContainerWithCheck creates and starts a PostgreSQL test container with Docker availability checks.
+// Docker is not checked when an external server is configured.
+func StartPostgreSQLContainerWithCheck(ctx context.Context, config *PostgreSQLConfig) (*PostgreSQLTestContainer, error) {
+	if config != nil && config.externalDatabaseURL() != "" {
+		return StartPostgreSQLContainer(ctx, config)
 	}
-}
 
-// StartPostgreSQLContainerWithCheck creates and starts a PostgreSQL test container with Docker availability checks
-func StartPostgreSQLContainerWithCheck(ctx context.Context, config *PostgreSQLConfig) (*PostgreSQLTestContainer, error) {
 	// Check Docker availability first
 	dockerStatus := CheckDockerAvailability()
 	if !dockerStatus.Available {
@@ -143,63 +155,188 @@ func StartPostgreSQLContainerWithCheck(ctx context.Context, config *PostgreSQLCo
 	return StartPostgreSQLContainer(ctx, config)
 }
 
-// StartPostgreSQLContainer creates and starts a PostgreSQL test container
+// StartPostgreSQLContainer creates and starts a PostgreSQL test container.
+// With ExternalDatabaseURL or $TEST_DATABASE_URL set, no container is started: a uniquely named
+// database is created on that server instead, and Close drops it.
 func StartPostgreSQLContainer(ctx context.Context, config *PostgreSQLConfig) (*PostgreSQLTestContainer, error) {
 	if config == nil {
 		config = DefaultPostgreSQLConfig()
 	}
 
+	if config.externalDatabaseURL() != "" {
+		return startExternalDatabase(ctx, config)
+	}
+
+	// Detected before testcontainers reads its configuration, so Ryuk settings take effect
+	runtimeInfo := containerRuntime()
+
+	// The server logs readiness twice on a fresh data directory: once for the init
+	// process and once for the real server
+	image := config.imageReference()
+	readyOccurrence := 2
+	if config.SnapshotCache {
+		// A snapshot already holds a prepared data directory, so the server starts once
+		var err error
+		image, err = ensureSnapshot(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		readyOccurrence = 1
+	}
+
+	opts := containerOptions(config, readyOccurrence)
+
+	// A reused container is shared with other test processes, so it is never terminated here
+	terminate := func(c *postgres.PostgresContainer) {
+		if !config.ReuseContainer {
+			_ = c.Terminate(ctx)
+		}
+	}
+
+	// testcontainers finds or creates the reusable container by name; the Docker daemon settles
+	// concurrent creations, wherever the test processes run
+	if config.ReuseContainer {
+		opts = append(opts, reuseOptions(config)...)
+	}
+
 	// Start PostgreSQL container with enhanced error handling
-	// Use PostGIS image for spatial queries (ST_DWithin, ST_MakePoint, etc.)
-	pgContainer, err := postgres.Run(ctx,
-		fmt.Sprintf("postgis/postgis:%s", config.PostgreSQLVersion),
-		postgres.WithDatabase(config.DatabaseName),
-		postgres.WithUsername(config.Username),
-		postgres.WithPassword(config.Password),
-		testcontainers.WithWaitStrategy(
-			wait.ForLog("database system is ready to accept connections").
-				WithOccurrence(2).
-				WithStartupTimeout(config.StartupTimeout),
-		),
-	)
+	// The image follows the configured family (PostGIS by default, for ST_DWithin, ST_MakePoint, etc.)
+	pgContainer, err := runContainer(ctx, image, opts, config.ReuseContainer)
 	if err != nil {
-		// Enhanced error handling with specific error types
-		if strings.Contains(err.Error(), "timeout") {
-			return nil, fmt.Errorf("%w: %v", ErrContainerStartTimeout, err)
+		return nil, err
+	}
+
+	// fail reports a failure after the container started, with its logs, and cleans up,
+	// dropping the package database of a reused container once it exists
+	var (
+		databaseName = config.DatabaseName
+		packageLock  *pgx.Conn
+	)
+	fail := func(phase StartupPhase, pool *pgxpool.Pool, err error) (*PostgreSQLTestContainer, error) {
+		startErr := newStartupError(ctx, phase, image, pgContainer.GetContainerID(), err)
+		if pool != nil {
+			pool.Close()
 		}
-		if strings.Contains(err.Error(), "port") && strings.Contains(err.Error(), "already in use") {
-			return nil, fmt.Errorf("%w: %v", ErrContainerPortConflict, err)
+		if packageLock != nil {
+			_ = releasePackageDatabase(context.WithoutCancel(ctx), packageLock, databaseName)
 		}
-		return nil, fmt.Errorf("failed to start PostgreSQL container: %w", err)
+		terminate(pgContainer) // Cleanup on error
+		return nil, startErr
 	}
 
 	// Get connection details
 	host, err := pgContainer.Host(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get container host: %w", err)
+		return fail(PhaseStart, nil, fmt.Errorf("failed to get container host: %w", err))
 	}
+	host = resolveHost(host, runtimeInfo)
 
 	port, err := pgContainer.MappedPort(ctx, "5432")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get container port: %w", err)
+		return fail(PhaseStart, nil, fmt.Errorf("failed to get container port: %w", err))
 	}
 
 	// Build database URL
 	databaseURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		config.Username, config.Password, host, port.Port(), config.DatabaseName)
+		config.Username, config.Password, host, port.Port(), databaseName)
+
+	// Give this package its own database inside the reused container
+	var adminDatabase string
+	if config.ReuseContainer {
+		if err := verifyReuseLabel(ctx, pgContainer, config); err != nil {
+			return fail(PhaseStart, nil, err)
+		}
 
-	// Run migrations if requested
-	if config.RunMigrations {
-		if err := runMigrations(databaseURL, config.MigrationsPath); err != nil {
-			_ = pgContainer.Terminate(ctx) // Cleanup on error
-			return nil, fmt.Errorf("%w: %v", ErrMigrationsFailed, err)
+		name, conn, err := createPackageDatabase(ctx, databaseURL, config)
+		if err != nil {
+			return fail(PhaseCreate, nil, err)
 		}
+		adminDatabase, databaseName, packageLock = databaseName, name, conn
+
+		databaseURL = fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+			config.Username, config.Password, host, port.Port(), databaseName)
 	}
 
 	// Create connection pool
+	pool, err := newPool(ctx, databaseURL, config)
+	if err != nil {
+		return fail(PhasePool, nil, err)
+	}
+
+	// Test the connection with enhanced error handling
+	if err := pool.Ping(ctx); err != nil {
+		return fail(PhasePing, pool, err)
+	}
+
+	// Install extensions, run init scripts, migrations and seed scripts.
+	// A snapshot image already contains their result.
+	if !config.SnapshotCache {
+		if err := prepareDatabase(ctx, pool, databaseURL, config, config.startupMigrator()); err != nil {
+			return fail(PhaseMigrate, pool, err)
+		}
+	}
+
+	if config.Cleaning.TrackDirtyTables {
+		if err := installDirtyTracking(ctx, pool); err != nil {
+			return fail(PhaseMigrate, pool, err)
+		}
+	}
+
+	return &PostgreSQLTestContainer{
+		Container:     pgContainer,
+		Pool:          pool,
+		DatabaseURL:   databaseURL,
+		Context:       ctx,
+		DatabaseName:  databaseName,
+		Username:      config.Username,
+		Password:      config.Password,
+		Runtime:       runtimeInfo,
+		config:        config,
+		adminDatabase: adminDatabase,
+		packageLock:   packageLock,
+	}, nil
+}
+
+// containerOptions returns the customizers shared by every container started for config.
+// readyOccurrence is how often the server logs readiness before it accepts connections.
+func containerOptions(config *PostgreSQLConfig, readyOccurrence int) []testcontainers.ContainerCustomizer {
+	opts := []testcontainers.ContainerCustomizer{
+		postgres.WithDatabase(config.DatabaseName),
+		postgres.WithUsername(config.Username),
+		postgres.WithPassword(config.Password),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(readyOccurrence).
+				WithStartupTimeout(config.StartupTimeout),
+		),
+	}
+
+	opts = append(opts, settingsOptions(config)...)
+
+	if config.SnapshotCache {
+		// docker commit skips volumes, so the data directory must live outside the image's VOLUME
+		opts = append(opts, testcontainers.WithEnv(map[string]string{"PGDATA": snapshotPGDATA}))
+	}
+
+	return opts
+}
+
+// startupMigrator returns the Migrator run at startup, or nil when migrations are disabled
+func (c *PostgreSQLConfig) startupMigrator() Migrator {
+	if !c.RunMigrations {
+		return nil
+	}
+	return c.migrator()
+}
+
+// newPool creates a connection pool for databaseURL using the pool settings from config
+func newPool(ctx context.Context, databaseURL string, config *PostgreSQLConfig) (*pgxpool.Pool, error) {
+	if config == nil {
+		config = DefaultPostgreSQLConfig()
+	}
+
 	poolConfig, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
-		_ = pgContainer.Terminate(ctx) // Cleanup on error
 		return nil, fmt.Errorf("failed to parse database URL: %w", err)
 	}
 
@@ -211,26 +348,10 @@ func StartPostgreSQLContainer(ctx context.Context, config *PostgreSQLConfig) (*P
 
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
-		_ = pgContainer.Terminate(ctx) // Cleanup on error
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
 
-	// Test the connection with enhanced error handling
-	if err := pool.Ping(ctx); err != nil {
-		pool.Close()
-		_ = pgContainer.Terminate(ctx) // Cleanup on error
-		return nil, fmt.Errorf("%w: %v", ErrDatabaseConnFailed, err)
-	}
-
-	return &PostgreSQLTestContainer{
-		Container:    pgContainer,
-		Pool:         pool,
-		DatabaseURL:  databaseURL,
-		Context:      ctx,
-		DatabaseName: config.DatabaseName,
-		Username:     config.Username,
-		Password:     config.Password,
-	}, nil
+	return pool, nil
 }
 
 // StartSimplePostgreSQLContainer creates a PostgreSQL container with default settings
@@ -247,15 +368,24 @@ func StartPostgreSQLContainerWithMigrations(ctx context.Context, migrationsPath
 	return StartPostgreSQLContainerWithCheck(ctx, config)
 }
 
-// Close closes the connection pool and terminates the container
+// Close drops the template and leaked test databases, closes the connection pool and terminates
+// the container. For a reused container or an external server only the databases are dropped.
 func (tc *PostgreSQLTestContainer) Close() error {
 	var errs []error
 
 	if tc.Pool != nil {
+		errs = append(errs, tc.dropLeakedDatabases(tc.Context)...)
+		if err := tc.dropTemplate(tc.Context); err != nil {
+			errs = append(errs, err)
+		}
 		tc.Pool.Close()
 	}
 
-	if tc.Container != nil {
+	if tc.adminDatabase != "" {
+		if err := tc.dropPackageDatabase(tc.Context); err != nil {
+			errs = append(errs, err)
+		}
+	} else if tc.Container != nil {
 		if err := tc.Container.Terminate(tc.Context); err != nil {
 			errs = append(errs, fmt.Errorf("failed to terminate container: %w", err))
 		}
@@ -268,141 +398,142 @@ func (tc *PostgreSQLTestContainer) Close() error {
 	return nil
 }
 
-// CleanAllTables truncates all tables in the database for test isolation
-// WARNING: This removes ALL data from ALL tables
-func (tc *PostgreSQLTestContainer) CleanAllTables(ctx context.Context) error {
-	// Get all table names, excluding system tables
-	rows, err := tc.Pool.Query(ctx, `
-		SELECT tablename
-		FROM pg_tables
-		WHERE schemaname = 'public'
-		AND tablename NOT IN (
-			'schema_migrations',
-			'spatial_ref_sys',
-			'geometry_columns',
-			'geography_columns'
-		)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to get table names: %w", err)
-	}
-	defer rows.Close()
-
-	var tables []string
-	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
-			return fmt.Errorf("failed to scan table name: %w", err)
-		}
-		tables = append(tables, tableName)
-	}
+// GetConnectionString returns the database connection string
+func (tc *PostgreSQLTestContainer) GetConnectionString() string {
+	return tc.DatabaseURL
+}
 
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating over table names: %w", err)
-	}
+// GetPool returns the connection pool
+func (tc *PostgreSQLTestContainer) GetPool() *pgxpool.Pool {
+	return tc.Pool
+}
 
-	// Truncate all tables
-	if len(tables) > 0 {
-		truncateSQL := "TRUNCATE " + tables[0]
-		for _, table := range tables[1:] {
-			truncateSQL += ", " + table
-		}
-		truncateSQL += " CASCADE"
+// GetContainer returns the testcontainers instance
+func (tc *PostgreSQLTestContainer) GetContainer() *postgres.PostgresContainer {
+	return tc.Container
+}
 
-		if _, err := tc.Pool.Exec(ctx, truncateSQL); err != nil {
-			return fmt.Errorf("failed to truncate tables: %w", err)
-		}
+// logf reports a warning through the configured logger
+func (c *PostgreSQLConfig) logf(format string, args ...any) {
+	if c != nil && c.Logf != nil {
+		c.Logf(format, args...)
+		return
 	}
+	fmt.Printf(format+"\n", args...)
+}
 
-	return nil
+// logf reports a warning through the logger of the container's configuration
+func (tc *PostgreSQLTestContainer) logf(format string, args ...any) {
+	tc.config.logf(format, args...)
 }
 
-// CleanSpecificTables truncates specific tables for test isolation
-// Only truncates tables that actually exist to avoid errors
-func (tc *PostgreSQLTestContainer) CleanSpecificTables(ctx context.Context, tableNames ...string) error {
-	if len(tableNames) == 0 {
-		return nil
+// prepareDatabase brings a new database up to date. Extensions are installed first so that
+// scripts and migrations can use them, then init scripts, migrations (when migrator is non-nil)
+// and seed scripts run in that order.
+func prepareDatabase(ctx context.Context, pool *pgxpool.Pool, databaseURL string, config *PostgreSQLConfig, migrator Migrator) error {
+	if err := installExtensions(ctx, pool, config.Extensions); err != nil {
+		return err
 	}
 
-	// Check which tables actually exist
-	var existingTables []string
-	for _, tableName := range tableNames {
-		var exists bool
-		err := tc.Pool.QueryRow(ctx,
-			"SELECT EXISTS (SELECT FROM information_schema.tables WHERE table_name = $1)",
-			tableName).Scan(&exists)
-		if err != nil {
-			return fmt.Errorf("failed to check if table %s exists: %w", tableName, err)
-		}
-		if exists {
-			existingTables = append(existingTables, tableName)
-		}
+	if err := runScriptPatterns(ctx, pool, config.ScriptsFS, config.InitScripts); err != nil {
+		return err
 	}
 
-	// Only truncate if we have existing tables
-	if len(existingTables) > 0 {
-		truncateSQL := "TRUNCATE " + existingTables[0]
-		for _, table := range existingTables[1:] {
-			truncateSQL += ", " + table
-		}
-		truncateSQL += " CASCADE"
-
-		if _, err := tc.Pool.Exec(ctx, truncateSQL); err != nil {
-			return fmt.Errorf("failed to truncate specific tables: %w", err)
+	if migrator != nil {
+		if err := migrator.Migrate(ctx, databaseURL, pool); err != nil {
+			return fmt.Errorf("%w: %v", ErrMigrationsFailed, err)
 		}
 	}
 
-	return nil
+	return runScriptPatterns(ctx, pool, config.ScriptsFS, config.SeedScripts)
 }
 
-// GetConnectionString returns the database connection string
-func (tc *PostgreSQLTestContainer) GetConnectionString() string {
-	return tc.DatabaseURL
+// migrationSource is where migrations are read from: an fs.FS subdirectory or a host path
+type migrationSource struct {
+	fsys fs.FS
+	dir  string // Subdirectory of fsys
+	path string // Host path, auto-detected if empty; used when fsys is nil
 }
 
-// GetPool returns the connection pool
-func (tc *PostgreSQLTestContainer) GetPool() *pgxpool.Pool {
-	return tc.Pool
+// migrationSource returns the configured migration source
+func (c *PostgreSQLConfig) migrationSource() migrationSource {
+	dir := c.MigrationsDir
+	if dir == "" {
+		dir = "."
+	}
+	return migrationSource{fsys: c.MigrationsFS, dir: dir, path: c.MigrationsPath}
 }
 
-// GetContainer returns the testcontainers instance
-func (tc *PostgreSQLTestContainer) GetContainer() *postgres.PostgresContainer {
-	return tc.Container
+// newMigrate creates a golang-migrate instance reading from source and applying to databaseURL
+// Warnings about the auto-detected migrations path go to logf.
+func newMigrate(source migrationSource, databaseURL string, logf func(format string, args ...any)) (*migrate.Migrate, error) {
+	if source.fsys != nil {
+		driver, err := iofs.New(source.fsys, source.dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open migrations in %s: %w", source.dir, err)
+		}
+		m, err := migrate.NewWithSourceInstance("iofs", driver, databaseURL)
+		if err != nil {
+			_ = driver.Close()
+			return nil, fmt.Errorf("failed to create migrate instance: %w", err)
+		}
+		return m, nil
+	}
+
+	migrationsPath, err := resolveMigrationsPath(source.path, logf)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.New(
+		fmt.Sprintf("file://%s?x-migrations-table=schema_migrations", filepath.ToSlash(migrationsPath)),
+		databaseURL,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+	return m, nil
 }
 
-// runMigrations applies database migrations
-func runMigrations(databaseURL, migrationsPath string) error {
+// resolveMigrationsPath returns the absolute host path of the migrations, auto-detecting it if
+// empty and reporting warnings through logf
+func resolveMigrationsPath(migrationsPath string, logf func(format string, args ...any)) (string, error) {
 	// Auto-detect migrations path if not provided
 	if migrationsPath == "" {
-		migrationsPath = FindMigrationsPath()
+		_, filename, _, _ := runtime.Caller(1) // The calling function, as FindMigrationsPath sees it
+		migrationsPath = findMigrationsPath(filename, logf)
 	}
 
 	// Convert to absolute path if relative
 	if !filepath.IsAbs(migrationsPath) {
 		absPath, err := filepath.Abs(migrationsPath)
 		if err != nil {
-			return fmt.Errorf("failed to get absolute path for migrations: %w", err)
+			return "", fmt.Errorf("failed to get absolute path for migrations: %w", err)
 		}
 		migrationsPath = absPath
 	}
 
-	// Create migrate instance
-	m, err := migrate.New(
-		fmt.Sprintf("file://%s?x-migrations-table=schema_migrations", migrationsPath),
-		databaseURL,
-	)
+	return migrationsPath, nil
+}
+
+// closeMigrate closes both sides of a migrate instance, reporting failures as warnings
+func closeMigrate(m *migrate.Migrate, logf func(format string, args ...any)) {
+	sourceErr, databaseErr := m.Close()
+	if sourceErr != nil {
+		logf("Warning: failed to close migrate source: %v", sourceErr)
+	}
+	if databaseErr != nil {
+		logf("Warning: failed to close migrate database: %v", databaseErr)
+	}
+}
+
+// runMigrations applies database migrations
+func runMigrations(databaseURL string, source migrationSource, logf func(format string, args ...any)) error {
+	m, err := newMigrate(source, databaseURL, logf)
 	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %w", err)
+		return err
 	}
-	defer func() {
-		sourceErr, databaseErr := m.Close()
-		if sourceErr != nil {
-			fmt.Printf("Warning: failed to close migrate source: %v\n", sourceErr)
-		}
-		if databaseErr != nil {
-			fmt.Printf("Warning: failed to close migrate database: %v\n", databaseErr)
-		}
-	}()
+	defer closeMigrate(m, logf)
 
 	// Run migrations
 	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
@@ -424,7 +555,16 @@ func runMigrations(databaseURL, migrationsPath string) error {
 // Supports both normal git repos (.git directory) and git worktrees (.git file).
 //
 // Returns "database/migrations" as fallback if no migrations directory found.
+// An invalid MIGRATIONS_PATH is reported on stdout; containers report it through their Logf.
 func FindMigrationsPath() string {
+	// Get the caller's file path
+	_, filename, _, _ := runtime.Caller(2) // Skip current and calling function
+	return findMigrationsPath(filename, (*PostgreSQLConfig)(nil).logf)
+}
+
+// findMigrationsPath implements FindMigrationsPath, searching from the source file filename and
+// reporting an invalid MIGRATIONS_PATH through logf
+func findMigrationsPath(filename string, logf func(format string, args ...any)) string {
 	// Check environment variable first for explicit override
 	if envPath := os.Getenv("MIGRATIONS_PATH"); envPath != "" {
 		absPath, err := filepath.Abs(envPath)
@@ -434,12 +574,9 @@ func FindMigrationsPath() string {
 			}
 		}
 		// If MIGRATIONS_PATH is set but invalid, log warning but continue with fallback
-		fmt.Printf("Warning: MIGRATIONS_PATH set but invalid: %s\n", envPath)
+		logf("Warning: MIGRATIONS_PATH set but invalid: %s", envPath)
 	}
 
-	// Get the caller's file path
-	_, filename, _, _ := runtime.Caller(2) // Skip current and calling function
-
 	// Common paths to check relative to the project root
 	paths := []string{
 		"database/migrations",
@@ -452,6 +589,13 @@ func FindMigrationsPath() string {
 	// We use .git instead of go.mod because this is a monorepo with multiple go.mod files
 	// Note: .git can be a directory (normal repo) or a file (git worktree with gitdir pointer)
 	dir := filepath.Dir(filename)
+	if !filepath.IsAbs(dir) {
+		// Built with -trimpath (or from a module cache): the caller's path is not on disk,
+		// so start from the working directory, which go test sets to the package directory
+		if wd, err := os.Getwd(); err == nil {
+			dir = wd
+		}
+	}
 	for {
 		// Check if this is project root (has .git - either directory or worktree file)
 		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
@@ -492,7 +636,7 @@ func SkipIfDockerUnavailable() (shouldSkip bool, skipMessage string) {
 func (tc *PostgreSQLTestContainer) WithCleanup() func() {
 	return func() {
 		if err := tc.Close(); err != nil {
-			fmt.Printf("Warning: failed to cleanup PostgreSQL container: %v\n", err)
+			tc.logf("Warning: failed to cleanup PostgreSQL container: %v", err)
 		}
 	}
 }
@@ -501,31 +645,18 @@ func (tc *PostgreSQLTestContainer) WithCleanup() func() {
 func (tc *PostgreSQLTestContainer) WithTableCleanup(tables ...string) func() {
 	return func() {
 		if err := tc.CleanSpecificTables(tc.Context, tables...); err != nil {
-			fmt.Printf("Warning: failed to clean tables: %v\n", err)
+			tc.logf("Warning: failed to clean tables: %v", err)
 		}
 	}
 }
 
-// NewTestDatabase creates a new database within the container for isolation
-// This is useful when you need multiple isolated databases in the same container
-func (tc *PostgreSQLTestContainer) NewTestDatabase(dbName string) (string, error) {
-	// Create the new database
-	createSQL := fmt.Sprintf("CREATE DATABASE %s", dbName)
-	if _, err := tc.Pool.Exec(tc.Context, createSQL); err != nil {
-		return "", fmt.Errorf("failed to create test database %s: %w", dbName, err)
-	}
-
-	// Build connection string for the new database
-	host, err := tc.Container.Host(tc.Context)
+// databaseURLFor returns the connection string for another database on the same server
+func (tc *PostgreSQLTestContainer) databaseURLFor(dbName string) (string, error) {
+	u, err := url.Parse(tc.DatabaseURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to get container host: %w", err)
+		return "", fmt.Errorf("failed to parse database URL: %w", err)
 	}
-
-	port, err := tc.Container.MappedPort(tc.Context, "5432")
-	if err != nil {
-		return "", fmt.Errorf("failed to get container port: %w", err)
-	}
-
-	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		tc.Username, tc.Password, host, port.Port(), dbName), nil
+	u.Path = "/" + dbName
+	u.RawPath = ""
+	return u.String(), nil
 }
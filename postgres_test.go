@@ -3,7 +3,10 @@ package postgres
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -20,8 +23,11 @@ func TestDefaultPostgreSQLConfig(t *testing.T) {
 	if config.Password != "testpass" {
 		t.Errorf("Expected Password to be testpass, got %s", config.Password)
 	}
-	if config.PostgreSQLVersion != "16-3.4" {
-		t.Errorf("Expected PostgreSQLVersion to be 16-3.4, got %s", config.PostgreSQLVersion)
+	if config.PostgreSQLVersion != "" {
+		t.Errorf("Expected PostgreSQLVersion to be left to the family, got %s", config.PostgreSQLVersion)
+	}
+	if image := config.imageReference(); image != "postgis/postgis:16-3.4" {
+		t.Errorf("Expected the default image to be postgis/postgis:16-3.4, got %s", image)
 	}
 	if config.MaxConns != 10 {
 		t.Errorf("Expected MaxConns to be 10, got %d", config.MaxConns)
@@ -204,6 +210,22 @@ func TestFindMigrationsPath(t *testing.T) {
 	}
 }
 
+func TestFindMigrationsPath_InvalidEnvLogged(t *testing.T) {
+	t.Setenv("MIGRATIONS_PATH", filepath.Join(t.TempDir(), "missing"))
+
+	var logged []string
+	logf := func(format string, args ...any) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+
+	if path := findMigrationsPath("", logf); path == "" {
+		t.Error("Expected a fallback path")
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], "MIGRATIONS_PATH set but invalid") {
+		t.Errorf("Expected the invalid MIGRATIONS_PATH to be reported through logf, got %q", logged)
+	}
+}
+
 func TestErrorTypes(t *testing.T) {
 	tests := []struct {
 		name string
@@ -235,6 +257,21 @@ func TestErrorTypes(t *testing.T) {
 			err:  ErrMigrationsFailed,
 			want: "database migrations failed",
 		},
+		{
+			name: "not deferrable",
+			err:  ErrNotDeferrable,
+			want: "foreign key in a reference cycle is not DEFERRABLE",
+		},
+		{
+			name: "preserved reference",
+			err:  ErrPreservedReference,
+			want: "preserved table references a cleaned table",
+		},
+		{
+			name: "invalid config",
+			err:  ErrInvalidConfig,
+			want: "invalid PostgreSQL configuration",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+const (
+	// reuseNamePrefix prefixes the deterministic name of reusable containers
+	reuseNamePrefix = "jp-testcontainers-postgres-"
+
+	// ReuseLabel is set on reusable containers to the fingerprint of their configuration
+	ReuseLabel = "org.jp-go-testcontainers-postgres.reuse"
+)
+
+// reuseContainerName returns the deterministic container name for config
+func reuseContainerName(config *PostgreSQLConfig) string {
+	return reuseNamePrefix + containerFingerprint(config)[:12]
+}
+
+// reuseOptions returns the customizers that make testcontainers find or create the reusable container
+func reuseOptions(config *PostgreSQLConfig) []testcontainers.ContainerCustomizer {
+	return []testcontainers.ContainerCustomizer{
+		testcontainers.WithReuseByName(reuseContainerName(config)),
+		testcontainers.WithLabels(map[string]string{ReuseLabel: containerFingerprint(config)}),
+	}
+}
+
+// acquireHostLock takes the host-wide lock called name, shared by all test processes
+func acquireHostLock(name string) (func(), error) {
+	path := filepath.Join(os.TempDir(), name+".lock")
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600) // #nosec G304 -- path is derived from a hash
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock %s: %w", path, err)
+	}
+
+	if err := lockFile(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", path, err)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			_ = unlockFile(f)
+			_ = f.Close()
+		})
+	}, nil
+}
+
+// verifyReuseLabel checks that a container found by name was created for the same configuration
+func verifyReuseLabel(ctx context.Context, ctr testcontainers.Container, config *PostgreSQLConfig) error {
+	inspect, err := ctr.Inspect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to inspect reused container: %w", err)
+	}
+
+	if got, want := inspect.Config.Labels[ReuseLabel], containerFingerprint(config); got != want {
+		return fmt.Errorf("container %s has reuse label %q, want %q", reuseContainerName(config), got, want)
+	}
+
+	return nil
+}
+
+// createPackageDatabase creates a database owned by this start of the current test package
+// inside a reused container and returns its name, with the admin connection that marks it as in
+// use. The connection holds a session advisory lock keyed to the database until
+// releasePackageDatabase; databases whose lock is free were left behind by a start that is gone,
+// wherever it ran, and are dropped.
+func createPackageDatabase(ctx context.Context, adminURL string, config *PostgreSQLConfig) (string, *pgx.Conn, error) {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	prefix := packageDatabasePrefix(config.DatabaseName, workingDir)
+	name, err := packageDatabaseName(prefix)
+	if err != nil {
+		return "", nil, err
+	}
+
+	conn, err := pgx.Connect(ctx, adminURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrDatabaseConnFailed, err)
+	}
+
+	// Take the lock before the database exists, so no other start can see it unowned
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock("+packageLockKey+")", name); err != nil {
+		conn.Close(ctx)
+		return "", nil, fmt.Errorf("failed to lock package database %s: %w", name, err)
+	}
+	if err := dropStalePackageDatabases(ctx, conn, prefix); err != nil {
+		conn.Close(ctx)
+		return "", nil, err
+	}
+	if _, err := conn.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{name}.Sanitize()); err != nil {
+		conn.Close(ctx)
+		return "", nil, fmt.Errorf("failed to create package database %s: %w", name, err)
+	}
+
+	return name, conn, nil
+}
+
+// packageLockKey is the advisory lock key of the package database named by $1
+const packageLockKey = "hashtextextended($1, 0)"
+
+// dropStalePackageDatabases drops the package's databases whose advisory lock is free, i.e. whose
+// owning connection has ended. Liveness is decided by the server, so starts from other hosts
+// sharing the Docker daemon are never mistaken for dead ones.
+func dropStalePackageDatabases(ctx context.Context, conn *pgx.Conn, prefix string) error {
+	rows, err := conn.Query(ctx, "SELECT datname FROM pg_database WHERE starts_with(datname, $1)", prefix+"_")
+	if err != nil {
+		return fmt.Errorf("failed to list package databases: %w", err)
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to list package databases: %w", err)
+	}
+
+	for _, name := range names {
+		var free bool
+		if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock("+packageLockKey+")", name).Scan(&free); err != nil {
+			return fmt.Errorf("failed to check package database %s: %w", name, err)
+		}
+		if !free {
+			continue
+		}
+		_, err := conn.Exec(ctx, "DROP DATABASE IF EXISTS "+pgx.Identifier{name}.Sanitize()+" WITH (FORCE)")
+		if _, unlockErr := conn.Exec(ctx, "SELECT pg_advisory_unlock("+packageLockKey+")", name); err == nil {
+			err = unlockErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to drop stale package database %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// releasePackageDatabase drops the package database name through the connection holding its
+// lock, then closes the connection, which releases the lock
+func releasePackageDatabase(ctx context.Context, conn *pgx.Conn, name string) error {
+	defer conn.Close(ctx)
+
+	if _, err := conn.Exec(ctx, "DROP DATABASE IF EXISTS "+pgx.Identifier{name}.Sanitize()+" WITH (FORCE)"); err != nil {
+		return fmt.Errorf("failed to drop package database %s: %w", name, err)
+	}
+	return nil
+}
+
+// dropPackageDatabase removes the package database when a reused container or external server is closed
+func (tc *PostgreSQLTestContainer) dropPackageDatabase(ctx context.Context) error {
+	conn := tc.packageLock
+	tc.packageLock = nil
+	if conn == nil {
+		adminURL, err := tc.databaseURLFor(tc.adminDatabase)
+		if err != nil {
+			return err
+		}
+
+		conn, err = pgx.Connect(ctx, adminURL)
+		if err != nil {
+			return fmt.Errorf("failed to connect to %s: %w", tc.adminDatabase, err)
+		}
+	}
+
+	return releasePackageDatabase(ctx, conn, tc.DatabaseName)
+}
+
+// packageDatabasePrefix derives a stable database name prefix for the package in workingDir.
+// go test runs each package binary in its package directory, so every package gets its own prefix.
+func packageDatabasePrefix(base, workingDir string) string {
+	sum := sha256.Sum256([]byte(workingDir))
+	suffix := "_" + hex.EncodeToString(sum[:])[:8]
+
+	slug := strings.Trim(nonIdentifierChars.ReplaceAllString(strings.ToLower(filepath.Base(workingDir)), "_"), "_")
+	name := base
+	if slug != "" {
+		name += "_" + slug
+	}
+	// Leave room for the owner suffix added by packageDatabaseName
+	if limit := maxIdentifierLength - len(suffix) - packageOwnerLength; len(name) > limit {
+		name = name[:limit]
+	}
+	return name + suffix
+}
+
+// packageOwnerLength is the length of the random _<hex> suffix of a package database name
+const packageOwnerLength = 17
+
+// packageDatabaseName returns a name for a new package database with a random suffix, so
+// concurrent starts never share a database, whichever host they run on
+func packageDatabaseName(prefix string) (string, error) {
+	random := make([]byte, (packageOwnerLength-1)/2)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate database name: %w", err)
+	}
+	return prefix + "_" + hex.EncodeToString(random), nil
+}
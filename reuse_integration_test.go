@@ -0,0 +1,114 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestReuseContainer(t *testing.T) {
+	ctx := context.Background()
+
+	config := DefaultPostgreSQLConfig()
+	config.ReuseContainer = true
+
+	first, err := StartPostgreSQLContainer(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to start reusable container: %v", err)
+	}
+	defer first.Container.Terminate(ctx)
+
+	second, err := StartPostgreSQLContainer(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to reuse container: %v", err)
+	}
+
+	if first.Container.GetContainerID() != second.Container.GetContainerID() {
+		t.Error("Expected the second start to reuse the existing container")
+	}
+	if first.DatabaseName == config.DatabaseName {
+		t.Error("Expected the package to get its own database inside the reused container")
+	}
+	if first.DatabaseName == second.DatabaseName {
+		t.Error("Expected each start to get its own database")
+	}
+
+	// Starting again must not drop the first start's database or disconnect its sessions
+	if _, err := first.Pool.Exec(ctx, "CREATE TABLE still_here (id int)"); err != nil {
+		t.Errorf("Expected the first database to survive the second start: %v", err)
+	}
+
+	// Closing drops the package database but leaves the container running
+	if err := second.Close(); err != nil {
+		t.Fatalf("Failed to close reused container: %v", err)
+	}
+
+	state, err := first.Container.State(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get container state: %v", err)
+	}
+	if !state.Running {
+		t.Error("Expected reused container to keep running after Close")
+	}
+
+	if err := first.Pool.Ping(ctx); err != nil {
+		t.Errorf("Expected the first database to survive closing the second: %v", err)
+	}
+	if err := first.Close(); err != nil {
+		t.Errorf("Failed to close first container: %v", err)
+	}
+}
+
+func TestReuseContainer_DropsStaleDatabases(t *testing.T) {
+	ctx := context.Background()
+
+	config := DefaultPostgreSQLConfig()
+	config.ReuseContainer = true
+
+	first, err := StartPostgreSQLContainer(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to start reusable container: %v", err)
+	}
+	defer first.Container.Terminate(ctx)
+	defer first.Close()
+
+	// A database nobody holds the lock of, as left by a start that was killed
+	workingDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	stale, err := packageDatabaseName(packageDatabasePrefix(config.DatabaseName, workingDir))
+	if err != nil {
+		t.Fatalf("Failed to generate name: %v", err)
+	}
+	if _, err := first.Pool.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{stale}.Sanitize()); err != nil {
+		t.Fatalf("Failed to create stale database: %v", err)
+	}
+
+	second, err := StartPostgreSQLContainer(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to reuse container: %v", err)
+	}
+	defer second.Close()
+
+	rows, err := first.Pool.Query(ctx, "SELECT datname FROM pg_database")
+	if err != nil {
+		t.Fatalf("Failed to list databases: %v", err)
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatalf("Failed to list databases: %v", err)
+	}
+
+	if slices.Contains(names, stale) {
+		t.Errorf("Expected the unlocked database %s to be dropped", stale)
+	}
+	if !slices.Contains(names, first.DatabaseName) {
+		t.Errorf("Expected the database %s of a live start to be kept", first.DatabaseName)
+	}
+}
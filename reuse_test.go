@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReuseContainerName(t *testing.T) {
+	a := DefaultPostgreSQLConfig()
+	b := DefaultPostgreSQLConfig()
+	b.MaxConns = 50 // Pool settings do not change the container
+
+	if reuseContainerName(a) != reuseContainerName(b) {
+		t.Error("Expected pool settings not to affect the reused container name")
+	}
+	if !strings.HasPrefix(reuseContainerName(a), reuseNamePrefix) {
+		t.Errorf("Expected name to start with %s, got %s", reuseNamePrefix, reuseContainerName(a))
+	}
+
+	b.PostgreSQLVersion = "15-3.4"
+	if reuseContainerName(a) == reuseContainerName(b) {
+		t.Error("Expected different images to use different containers")
+	}
+}
+
+func TestPackageDatabasePrefix(t *testing.T) {
+	users := packageDatabasePrefix("testdb", "/src/project/internal/users")
+	orders := packageDatabasePrefix("testdb", "/src/project/internal/orders")
+
+	if users == orders {
+		t.Error("Expected different packages to get different prefixes")
+	}
+	if users != packageDatabasePrefix("testdb", "/src/project/internal/users") {
+		t.Error("Expected package database prefix to be stable")
+	}
+	if !strings.HasPrefix(users, "testdb_users_") {
+		t.Errorf("Expected prefix to include the package directory, got %s", users)
+	}
+
+	long := packageDatabasePrefix("testdb", "/src/"+strings.Repeat("very-long-package-name", 5))
+	name, err := packageDatabaseName(long)
+	if err != nil {
+		t.Fatalf("Failed to generate name: %v", err)
+	}
+	if len(name) > maxIdentifierLength {
+		t.Errorf("Expected name to be at most %d characters, got %d", maxIdentifierLength, len(name))
+	}
+}
+
+func TestPackageDatabaseName(t *testing.T) {
+	prefix := packageDatabasePrefix("testdb", "/src/project/internal/users")
+
+	first, err := packageDatabaseName(prefix)
+	if err != nil {
+		t.Fatalf("Failed to generate name: %v", err)
+	}
+	second, err := packageDatabaseName(prefix)
+	if err != nil {
+		t.Fatalf("Failed to generate name: %v", err)
+	}
+
+	if first == second {
+		t.Error("Expected each start to get its own database")
+	}
+	if !strings.HasPrefix(first, prefix+"_") || len(first) != len(prefix)+packageOwnerLength {
+		t.Errorf("Expected %s followed by a %d character suffix, got %s", prefix, packageOwnerLength, first)
+	}
+}
+
+func TestAcquireHostLock(t *testing.T) {
+	name := "locktest_" + strings.ReplaceAll(t.Name(), "/", "_")
+
+	unlock, err := acquireHostLock(name)
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	unlock()
+	unlock() // Releasing twice is safe
+
+	// The lock can be taken again once released
+	unlock, err = acquireHostLock(name)
+	if err != nil {
+		t.Fatalf("Failed to re-acquire lock: %v", err)
+	}
+	unlock()
+}
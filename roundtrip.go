@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RoundTripError reports a migration whose down or up step did not restore the expected schema.
+// It matches ErrMigrationRoundTrip with errors.Is.
+type RoundTripError struct {
+	Version   uint   // The migration that was stepped down or up
+	Direction string // "down" or "up"
+	Diff      string // Lines prefixed "- " are expected but missing, "+ " are unexpected
+}
+
+func (e *RoundTripError) Error() string {
+	want := "the schema from before it was applied"
+	if e.Direction == "up" {
+		want = "the schema it originally produced"
+	}
+	return fmt.Sprintf("%v: %s migration %d did not restore %s (- expected, + actual):\n%s",
+		ErrMigrationRoundTrip, e.Direction, e.Version, want, e.Diff)
+}
+
+// Is reports whether target is ErrMigrationRoundTrip
+func (e *RoundTripError) Is(target error) bool {
+	return target == ErrMigrationRoundTrip
+}
+
+// VerifyMigrationRoundTrip checks that every down migration reverses its up migration.
+//
+// In a scratch database it applies the migrations one at a time, snapshotting the schema
+// after each. It then walks back from the latest version: each down step must restore the
+// previous snapshot and re-applying the up step must reproduce its own snapshot. The first
+// mismatch is returned as a *RoundTripError with a diff of tables, columns, indexes,
+// constraints, functions and triggers.
+//
+// A non-empty migrationsPath is used instead of the container's configured golang-migrate
+// migrations. Extensions and init scripts are installed in the scratch database first.
+func (tc *PostgreSQLTestContainer) VerifyMigrationRoundTrip(ctx context.Context, migrationsPath string) error {
+	source, err := tc.golangMigrateSource(migrationsPath)
+	if err != nil {
+		return err
+	}
+
+	name := cloneDatabaseName(tc.DatabaseName, "roundtrip", tc.cloneSeq.Add(1))
+	if _, err := tc.Pool.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{name}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to create round trip database %s: %w", name, err)
+	}
+	defer tc.dropDatabase(context.WithoutCancel(ctx), name)
+
+	databaseURL, err := tc.databaseURLFor(name)
+	if err != nil {
+		return err
+	}
+
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseConnFailed, err)
+	}
+	defer pool.Close()
+
+	config := tc.config
+	if config == nil {
+		config = &PostgreSQLConfig{}
+	}
+	if err := installExtensions(ctx, pool, config.Extensions); err != nil {
+		return err
+	}
+	if err := runScriptPatterns(ctx, pool, config.ScriptsFS, config.InitScripts); err != nil {
+		return err
+	}
+
+	m, err := newMigrate(source, databaseURL, tc.logf)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMigrationsFailed, err)
+	}
+	defer closeMigrate(m, tc.logf)
+
+	snapshot := func() (schemaSnapshot, error) {
+		return snapshotSchema(ctx, pool, []string{golangMigrateTable})
+	}
+
+	// snapshots[i] is the schema after versions[:i] are applied
+	initial, err := snapshot()
+	if err != nil {
+		return err
+	}
+	snapshots := []schemaSnapshot{initial}
+	var versions []uint
+
+	for {
+		if err := m.Steps(1); errors.Is(err, os.ErrNotExist) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("%w: %v", ErrMigrationsFailed, err)
+		}
+
+		version, _, err := m.Version()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrMigrationsFailed, err)
+		}
+		s, err := snapshot()
+		if err != nil {
+			return err
+		}
+		versions = append(versions, version)
+		snapshots = append(snapshots, s)
+	}
+
+	for i := len(versions) - 1; i >= 0; i-- {
+		if err := tc.stepAndCompare(m, -1, snapshot, snapshots[i], versions[i], "down"); err != nil {
+			return err
+		}
+		if err := tc.stepAndCompare(m, 1, snapshot, snapshots[i+1], versions[i], "up"); err != nil {
+			return err
+		}
+		if err := m.Steps(-1); err != nil {
+			return fmt.Errorf("%w: down migration %d: %v", ErrMigrationsFailed, versions[i], err)
+		}
+	}
+
+	return nil
+}
+
+// stepAndCompare migrates n steps and compares the resulting schema with want
+func (tc *PostgreSQLTestContainer) stepAndCompare(m *migrate.Migrate, n int, snapshot func() (schemaSnapshot, error), want schemaSnapshot, version uint, direction string) error {
+	if err := m.Steps(n); err != nil {
+		return fmt.Errorf("%w: %s migration %d: %v", ErrMigrationsFailed, direction, version, err)
+	}
+
+	got, err := snapshot()
+	if err != nil {
+		return err
+	}
+	if diff := diffSchemas(want, got); diff != "" {
+		return &RoundTripError{Version: version, Direction: direction, Diff: diff}
+	}
+
+	return nil
+}
+
+// golangMigrateSource returns migrationsPath as a source, or the container's configured
+// golang-migrate source when it is empty
+func (tc *PostgreSQLTestContainer) golangMigrateSource(migrationsPath string) (migrationSource, error) {
+	if migrationsPath != "" {
+		return migrationSource{path: migrationsPath}, nil
+	}
+
+	migrator, ok := tc.config.migrator().(*golangMigrator)
+	if !ok {
+		return migrationSource{}, fmt.Errorf("%w: %T is not supported, only golang-migrate migrations", ErrMigrationsFailed, tc.config.migrator())
+	}
+	return migrator.source, nil
+}
@@ -0,0 +1,73 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeMigrations(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write migration file: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestVerifyMigrationRoundTrip(t *testing.T) {
+	tc := New(t)
+
+	dir := writeMigrations(t, map[string]string{
+		"001_users.up.sql":   "CREATE TABLE users (id SERIAL PRIMARY KEY, email TEXT NOT NULL);",
+		"001_users.down.sql": "DROP TABLE users;",
+		"002_email_index.up.sql": `
+CREATE UNIQUE INDEX users_email ON users (email);
+CREATE FUNCTION lower_email() RETURNS trigger AS $$
+BEGIN
+  NEW.email := lower(NEW.email);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER users_lower_email BEFORE INSERT ON users FOR EACH ROW EXECUTE FUNCTION lower_email();`,
+		"002_email_index.down.sql": "DROP TRIGGER users_lower_email ON users; DROP FUNCTION lower_email(); DROP INDEX users_email;",
+	})
+
+	if err := tc.VerifyMigrationRoundTrip(context.Background(), dir); err != nil {
+		t.Errorf("Expected round trip to succeed, got %v", err)
+	}
+}
+
+func TestVerifyMigrationRoundTrip_IncompleteDown(t *testing.T) {
+	tc := New(t)
+
+	dir := writeMigrations(t, map[string]string{
+		"001_users.up.sql":        "CREATE TABLE users (id SERIAL PRIMARY KEY);",
+		"001_users.down.sql":      "DROP TABLE users;",
+		"002_add_email.up.sql":    "ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT ''; CREATE INDEX users_email ON users (email);",
+		"002_add_email.down.sql":  "DROP INDEX users_email;",
+		"003_add_status.up.sql":   "ALTER TABLE users ADD COLUMN status TEXT;",
+		"003_add_status.down.sql": "ALTER TABLE users DROP COLUMN status;",
+	})
+
+	err := tc.VerifyMigrationRoundTrip(context.Background(), dir)
+
+	var roundTripErr *RoundTripError
+	if !errors.As(err, &roundTripErr) {
+		t.Fatalf("Expected RoundTripError, got %v", err)
+	}
+	if roundTripErr.Version != 2 || roundTripErr.Direction != "down" {
+		t.Errorf("Expected down migration 2 to fail, got %s migration %d", roundTripErr.Direction, roundTripErr.Version)
+	}
+	if !strings.Contains(roundTripErr.Diff, "+ column public.users.email text not null default ''::text") {
+		t.Errorf("Expected diff to show the leftover email column, got:\n%s", roundTripErr.Diff)
+	}
+}
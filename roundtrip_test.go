@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	want := schemaSnapshot{
+		"column public.users.email text not null",
+		"column public.users.id integer not null",
+		"index public.users_email CREATE UNIQUE INDEX users_email ON public.users USING btree (email)",
+		"table public.users",
+	}
+	got := schemaSnapshot{
+		"column public.users.email text",
+		"column public.users.id integer not null",
+		"table public.users",
+	}
+
+	diff := diffSchemas(want, got)
+	wantDiff := "+ column public.users.email text\n" +
+		"- column public.users.email text not null\n" +
+		"- index public.users_email CREATE UNIQUE INDEX users_email ON public.users USING btree (email)\n"
+	if diff != wantDiff {
+		t.Errorf("Expected diff:\n%s\ngot:\n%s", wantDiff, diff)
+	}
+
+	if diff := diffSchemas(want, want); diff != "" {
+		t.Errorf("Expected no diff for identical snapshots, got %q", diff)
+	}
+}
+
+func TestRoundTripError(t *testing.T) {
+	err := &RoundTripError{Version: 3, Direction: "down", Diff: "+ table public.leftover\n"}
+
+	if !errors.Is(err, ErrMigrationRoundTrip) {
+		t.Error("Expected RoundTripError to match ErrMigrationRoundTrip")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "down migration 3 did not restore the schema from before it was applied") {
+		t.Errorf("Expected message to name the failing migration, got %s", msg)
+	}
+	if !strings.HasSuffix(msg, "+ table public.leftover\n") {
+		t.Errorf("Expected message to end with the diff, got %s", msg)
+	}
+}
+
+func TestGolangMigrateSource(t *testing.T) {
+	tc := &PostgreSQLTestContainer{config: &PostgreSQLConfig{MigrationsPath: "db/migrations"}}
+
+	source, err := tc.golangMigrateSource("")
+	if err != nil || source.path != "db/migrations" {
+		t.Errorf("Expected configured path, got %+v, %v", source, err)
+	}
+
+	source, err = tc.golangMigrateSource("other")
+	if err != nil || source.path != "other" {
+		t.Errorf("Expected explicit path, got %+v, %v", source, err)
+	}
+
+	tc.config.Migrator = NewGooseMigrator(fstest.MapFS{}, ".")
+	if _, err := tc.golangMigrateSource(""); err == nil {
+		t.Error("Expected error for a non golang-migrate migrator")
+	}
+}
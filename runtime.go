@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// runtimeProbeTimeout bounds each request made while detecting the container runtime
+const runtimeProbeTimeout = 5 * time.Second
+
+// RuntimeInfo describes the container runtime containers are started on and the adjustments
+// made for it
+type RuntimeInfo struct {
+	Runtime  ContainerRuntime
+	Host     string // Docker host, e.g. unix:///run/user/1000/podman/podman.sock
+	Rootless bool
+	SELinux  bool
+
+	// Adjustments lists the settings testcontainers was configured with for the runtime, as KEY=value
+	Adjustments []string
+}
+
+// containerRuntime detects the runtime and configures testcontainers for it, once per process.
+// It must run before testcontainers first reads its configuration, which it caches. The process
+// environment is left unchanged.
+var containerRuntime = sync.OnceValue(func() RuntimeInfo {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*runtimeProbeTimeout)
+	defer cancel()
+
+	info, discovered := probeRuntime(ctx)
+
+	settings := runtimeEnv(info)
+	if discovered {
+		settings["DOCKER_HOST"] = info.Host
+	}
+	if len(settings) > 0 {
+		info.Adjustments = configureTestcontainers(ctx, settings)
+	}
+
+	return info
+})
+
+// probeRuntime finds the Docker API and identifies the engine behind it. Without DOCKER_HOST the
+// default socket is tried first, then the sockets of rootless Docker, Docker Desktop, Podman,
+// Colima, Rancher Desktop and OrbStack; discovered reports whether a non-default socket answered.
+func probeRuntime(ctx context.Context) (info RuntimeInfo, discovered bool) {
+	dockerHost := os.Getenv("DOCKER_HOST")
+	info = RuntimeInfo{Runtime: RuntimeUnknown, Host: dockerHost}
+
+	hosts := []string{dockerHost}
+	if dockerHost == "" {
+		home, _ := os.UserHomeDir()
+		hosts = nil
+		for _, socket := range dockerSocketCandidates(home, os.Getenv("XDG_RUNTIME_DIR")) {
+			if _, err := os.Stat(socket); err == nil {
+				hosts = append(hosts, "unix://"+socket)
+			}
+		}
+	}
+
+	for _, host := range hosts {
+		if identifyRuntime(ctx, host, &info) {
+			return info, dockerHost == "" && host != client.DefaultDockerHost
+		}
+	}
+	return info, false
+}
+
+// identifyRuntime queries the engine at host and records what it finds in info
+func identifyRuntime(ctx context.Context, host string, info *RuntimeInfo) bool {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithHost(host), client.WithAPIVersionNegotiation())
+	if err != nil {
+		return false
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, runtimeProbeTimeout)
+	defer cancel()
+
+	engine, err := cli.Info(ctx)
+	if err != nil {
+		return false
+	}
+	version, _ := cli.ServerVersion(ctx)
+
+	info.Host = host
+	info.Runtime = detectRuntime(engine, version, host)
+	info.Rootless = slices.Contains(engine.SecurityOptions, "name=rootless")
+	info.SELinux = slices.Contains(engine.SecurityOptions, "name=selinux")
+	return true
+}
+
+// dockerSocketCandidates lists the sockets the Docker API is commonly served on, default first
+func dockerSocketCandidates(home, runtimeDir string) []string {
+	candidates := []string{"/var/run/docker.sock"}
+	if runtimeDir != "" {
+		candidates = append(candidates, filepath.Join(runtimeDir, "docker.sock"))
+	}
+	if home != "" {
+		candidates = append(candidates,
+			filepath.Join(home, ".docker", "run", "docker.sock"),
+			filepath.Join(home, ".docker", "desktop", "docker.sock"),
+		)
+	}
+	if runtimeDir != "" {
+		candidates = append(candidates, filepath.Join(runtimeDir, "podman", "podman.sock"))
+	}
+	candidates = append(candidates, "/run/podman/podman.sock")
+	if home != "" {
+		candidates = append(candidates,
+			filepath.Join(home, ".local", "share", "containers", "podman", "machine", "podman.sock"),
+			filepath.Join(home, ".colima", "default", "docker.sock"),
+			filepath.Join(home, ".colima", "docker.sock"),
+			filepath.Join(home, ".rd", "docker.sock"),
+			filepath.Join(home, ".orbstack", "run", "docker.sock"),
+		)
+	}
+	return candidates
+}
+
+// runtimeEnv returns the testcontainers settings the runtime needs.
+// No bind mount needs an SELinux relabel (:z): the PostgreSQL container only uses tmpfs and files
+// copied into it, and the one bind mount, Ryuk's Docker socket, is created by testcontainers,
+// which offers no mount option for it, so Ryuk runs privileged, which disables its labelling.
+func runtimeEnv(info RuntimeInfo) map[string]string {
+	env := make(map[string]string)
+
+	switch info.Runtime {
+	case RuntimePodman:
+		// Ryuk mounts the Podman socket, which needs a privileged container. A Podman machine's
+		// socket is forwarded from a VM that has no socket at the host path, so Ryuk cannot run.
+		env["TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED"] = "true"
+		if strings.Contains(info.Host, "/podman/machine/") {
+			env["TESTCONTAINERS_RYUK_DISABLED"] = "true"
+		}
+	case RuntimeColima, RuntimeRancherDesktop:
+		// The host socket path does not exist inside the VM, where Ryuk mounts it from
+		env["TESTCONTAINERS_DOCKER_SOCKET_OVERRIDE"] = "/var/run/docker.sock"
+	}
+
+	if info.SELinux {
+		// SELinux denies an unprivileged container access to the mounted socket
+		env["TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED"] = "true"
+	}
+
+	return env
+}
+
+// configureTestcontainers makes testcontainers read its configuration, Docker host and socket
+// with settings in place of unset environment variables, and returns the settings used as sorted
+// KEY=value pairs. testcontainers caches all three for the lifetime of the process, so the
+// environment is restored as soon as they are read; variables set explicitly always win.
+func configureTestcontainers(ctx context.Context, settings map[string]string) (applied []string) {
+	applied, restore := overlayEnv(settings)
+	defer restore()
+
+	// A host that stopped answering makes testcontainers panic; starting a container reports it
+	defer func() { _ = recover() }()
+
+	testcontainers.ReadConfig()
+	if cli, err := testcontainers.NewDockerClientWithOpts(ctx); err == nil {
+		_ = cli.Close()
+	}
+	if !testcontainers.ReadConfig().RyukDisabled {
+		testcontainers.MustExtractDockerSocket(ctx)
+	}
+
+	return applied
+}
+
+// overlayEnv sets every variable in env that is not already set and returns the applied settings
+// as sorted KEY=value pairs, with a function that unsets them again
+func overlayEnv(env map[string]string) ([]string, func()) {
+	var applied, keys []string
+	for key, value := range env {
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, value); err == nil {
+			applied = append(applied, key+"="+value)
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(applied)
+
+	return applied, func() {
+		for _, key := range keys {
+			_ = os.Unsetenv(key)
+		}
+	}
+}
+
+// resolveHost returns the host to connect to. Podman and rootless Docker publish ports on IPv4
+// only, while localhost may resolve to ::1 first.
+func resolveHost(host string, info RuntimeInfo) string {
+	if strings.EqualFold(host, "localhost") && (info.Runtime == RuntimePodman || info.Rootless) {
+		return "127.0.0.1"
+	}
+	return host
+}
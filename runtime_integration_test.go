@@ -0,0 +1,38 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestContainerRuntime(t *testing.T) {
+	tc := New(t, WithImageFamily(ImageFamilyPostgres, ""))
+
+	if tc.Runtime.Runtime == "" || tc.Runtime.Runtime == RuntimeUnknown {
+		t.Errorf("Expected the runtime to be detected, got %+v", tc.Runtime)
+	}
+	if tc.Runtime.Host == "" {
+		t.Error("Expected the Docker host to be recorded")
+	}
+
+	// The resolved host must accept connections on every runtime
+	if err := tc.Pool.Ping(context.Background()); err != nil {
+		t.Errorf("Failed to ping through %s: %v", tc.DatabaseURL, err)
+	}
+
+	// The settings only configured testcontainers; the process environment is unchanged
+	for _, adjustment := range tc.Runtime.Adjustments {
+		key, _, _ := strings.Cut(adjustment, "=")
+		if _, set := os.LookupEnv(key); set {
+			t.Errorf("Expected %s to be left unset in the environment", key)
+		}
+	}
+
+	if docker := CheckDockerAvailability(); docker.Runtime != tc.Runtime.Runtime {
+		t.Errorf("Expected the availability check to report %s, got %s", tc.Runtime.Runtime, docker.Runtime)
+	}
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestDockerSocketCandidates(t *testing.T) {
+	candidates := dockerSocketCandidates("/home/dev", "/run/user/1000")
+
+	if candidates[0] != "/var/run/docker.sock" {
+		t.Errorf("Expected the default socket first, got %s", candidates[0])
+	}
+	for _, want := range []string{
+		"/run/user/1000/docker.sock",
+		"/run/user/1000/podman/podman.sock",
+		"/run/podman/podman.sock",
+		"/home/dev/.colima/default/docker.sock",
+		"/home/dev/.rd/docker.sock",
+	} {
+		if !slices.Contains(candidates, want) {
+			t.Errorf("Expected %s to be a candidate, got %v", want, candidates)
+		}
+	}
+
+	if got := dockerSocketCandidates("", ""); !slices.Equal(got, []string{"/var/run/docker.sock", "/run/podman/podman.sock"}) {
+		t.Errorf("Expected only absolute sockets without home and runtime dir, got %v", got)
+	}
+}
+
+func TestRuntimeEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		info RuntimeInfo
+		want map[string]string
+	}{
+		{
+			name: "docker",
+			info: RuntimeInfo{Runtime: RuntimeDocker},
+			want: map[string]string{},
+		},
+		{
+			name: "rootless docker",
+			info: RuntimeInfo{Runtime: RuntimeDocker, Rootless: true},
+			want: map[string]string{},
+		},
+		{
+			name: "podman",
+			info: RuntimeInfo{Runtime: RuntimePodman, Host: "unix:///run/user/1000/podman/podman.sock"},
+			want: map[string]string{"TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED": "true"},
+		},
+		{
+			name: "podman machine",
+			info: RuntimeInfo{Runtime: RuntimePodman, Host: "unix:///Users/dev/.local/share/containers/podman/machine/podman.sock"},
+			want: map[string]string{
+				"TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED": "true",
+				"TESTCONTAINERS_RYUK_DISABLED":             "true",
+			},
+		},
+		{
+			name: "colima",
+			info: RuntimeInfo{Runtime: RuntimeColima},
+			want: map[string]string{"TESTCONTAINERS_DOCKER_SOCKET_OVERRIDE": "/var/run/docker.sock"},
+		},
+		{
+			name: "selinux",
+			info: RuntimeInfo{Runtime: RuntimeDocker, SELinux: true},
+			want: map[string]string{"TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED": "true"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runtimeEnv(tt.info)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("Expected %s=%s, got %q", key, value, got[key])
+				}
+			}
+		})
+	}
+}
+
+func TestOverlayEnv(t *testing.T) {
+	t.Setenv("TESTCONTAINERS_RYUK_DISABLED", "false")
+	t.Setenv("TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED", "")
+	os.Unsetenv("TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED") // Restored by t.Setenv
+
+	applied, restore := overlayEnv(map[string]string{
+		"TESTCONTAINERS_RYUK_DISABLED":             "true",
+		"TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED": "true",
+	})
+
+	if !slices.Equal(applied, []string{"TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED=true"}) {
+		t.Errorf("Expected only the unset variable to be applied, got %v", applied)
+	}
+	if got := os.Getenv("TESTCONTAINERS_RYUK_DISABLED"); got != "false" {
+		t.Errorf("Expected an explicit setting to win, got %s", got)
+	}
+	if got := os.Getenv("TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED"); got != "true" {
+		t.Errorf("Expected TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED=true, got %q", got)
+	}
+
+	restore()
+	if _, set := os.LookupEnv("TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED"); set {
+		t.Error("Expected the applied variable to be unset again")
+	}
+	if got := os.Getenv("TESTCONTAINERS_RYUK_DISABLED"); got != "false" {
+		t.Errorf("Expected the explicit setting to be kept, got %s", got)
+	}
+}
+
+func TestResolveHost(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		info RuntimeInfo
+		want string
+	}{
+		{name: "docker", host: "localhost", info: RuntimeInfo{Runtime: RuntimeDocker}, want: "localhost"},
+		{name: "podman", host: "localhost", info: RuntimeInfo{Runtime: RuntimePodman}, want: "127.0.0.1"},
+		{name: "rootless docker", host: "localhost", info: RuntimeInfo{Runtime: RuntimeDocker, Rootless: true}, want: "127.0.0.1"},
+		{name: "remote host", host: "10.0.0.5", info: RuntimeInfo{Runtime: RuntimePodman}, want: "10.0.0.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveHost(tt.host, tt.info); got != tt.want {
+				t.Errorf("Expected host %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// schemaSnapshot is a sorted, line-per-object description of a database's user-defined schema
+type schemaSnapshot []string
+
+// userObjectFilter restricts catalog queries to user schemas and to objects not owned by extensions.
+// %[1]s is the namespace alias, %[2]s the object's oid column and %[3]s its catalog.
+const userObjectFilter = `
+	%[1]s.nspname NOT IN ('pg_catalog', 'information_schema', '` + trackingSchema + `')
+	AND %[1]s.nspname NOT LIKE 'pg\_toast%%'
+	AND %[1]s.nspname NOT LIKE 'pg\_temp\_%%'
+	AND NOT EXISTS (
+		SELECT 1 FROM pg_depend dep
+		WHERE dep.classid = '%[3]s'::regclass AND dep.objid = %[2]s AND dep.deptype = 'e'
+	)`
+
+// schemaQueries describe each kind of object. $1, where used, holds table names to leave out.
+var schemaQueries = []struct {
+	kind  string
+	query string
+}{
+	{
+		kind: "tables",
+		query: `
+			SELECT format('%s %I.%I',
+				CASE c.relkind WHEN 'r' THEN 'table' WHEN 'p' THEN 'partitioned table'
+					WHEN 'v' THEN 'view' ELSE 'materialized view' END,
+				n.nspname, c.relname)
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relkind IN ('r', 'p', 'v', 'm')
+			AND c.relname <> ALL($1)
+			AND ` + fmt.Sprintf(userObjectFilter, "n", "c.oid", "pg_class"),
+	},
+	{
+		kind: "columns",
+		query: `
+			SELECT format('column %I.%I.%I %s%s%s',
+				n.nspname, c.relname, a.attname,
+				format_type(a.atttypid, a.atttypmod),
+				CASE WHEN a.attnotnull THEN ' not null' ELSE '' END,
+				COALESCE(' default ' || pg_get_expr(d.adbin, d.adrelid), ''))
+			FROM pg_attribute a
+			JOIN pg_class c ON c.oid = a.attrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+			WHERE a.attnum > 0 AND NOT a.attisdropped
+			AND c.relkind IN ('r', 'p', 'v', 'm')
+			AND c.relname <> ALL($1)
+			AND ` + fmt.Sprintf(userObjectFilter, "n", "c.oid", "pg_class"),
+	},
+	{
+		kind: "indexes",
+		query: `
+			SELECT format('index %I.%I %s', n.nspname, ic.relname, pg_get_indexdef(i.indexrelid))
+			FROM pg_index i
+			JOIN pg_class ic ON ic.oid = i.indexrelid
+			JOIN pg_class c ON c.oid = i.indrelid
+			JOIN pg_namespace n ON n.oid = ic.relnamespace
+			WHERE c.relname <> ALL($1)
+			AND ` + fmt.Sprintf(userObjectFilter, "n", "c.oid", "pg_class"),
+	},
+	{
+		kind: "constraints",
+		query: `
+			SELECT format('constraint %I.%I.%I %s', n.nspname, c.relname, con.conname, pg_get_constraintdef(con.oid))
+			FROM pg_constraint con
+			JOIN pg_class c ON c.oid = con.conrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relname <> ALL($1)
+			AND ` + fmt.Sprintf(userObjectFilter, "n", "c.oid", "pg_class"),
+	},
+	{
+		kind: "functions",
+		query: `
+			SELECT format('%s %I.%I(%s)%s language %s body %s',
+				CASE p.prokind WHEN 'p' THEN 'procedure' WHEN 'a' THEN 'aggregate' ELSE 'function' END,
+				n.nspname, p.proname, pg_get_function_identity_arguments(p.oid),
+				COALESCE(' returns ' || pg_get_function_result(p.oid), ''),
+				l.lanname, md5(p.prosrc))
+			FROM pg_proc p
+			JOIN pg_namespace n ON n.oid = p.pronamespace
+			JOIN pg_language l ON l.oid = p.prolang
+			WHERE ` + fmt.Sprintf(userObjectFilter, "n", "p.oid", "pg_proc"),
+	},
+	{
+		kind: "views",
+		query: `
+			SELECT format('view definition %I.%I %s', n.nspname, c.relname, btrim(regexp_replace(pg_get_viewdef(c.oid), '\s+', ' ', 'g')))
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relkind IN ('v', 'm')
+			AND c.relname <> ALL($1)
+			AND ` + fmt.Sprintf(userObjectFilter, "n", "c.oid", "pg_class"),
+	},
+	{
+		kind: "types",
+		query: `
+			SELECT format('enum %I.%I (%s)', n.nspname, t.typname,
+				(SELECT string_agg(quote_literal(e.enumlabel), ', ' ORDER BY e.enumsortorder) FROM pg_enum e WHERE e.enumtypid = t.oid))
+			FROM pg_type t
+			JOIN pg_namespace n ON n.oid = t.typnamespace
+			WHERE t.typtype = 'e'
+			AND ` + fmt.Sprintf(userObjectFilter, "n", "t.oid", "pg_type"),
+	},
+	{
+		kind: "triggers",
+		query: `
+			SELECT format('trigger %I.%I.%I %s', n.nspname, c.relname, t.tgname, pg_get_triggerdef(t.oid))
+			FROM pg_trigger t
+			JOIN pg_class c ON c.oid = t.tgrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE NOT t.tgisinternal
+			AND t.tgname <> '` + trackingTrigger + `'
+			AND c.relname <> ALL($1)
+			AND ` + fmt.Sprintf(userObjectFilter, "n", "c.oid", "pg_class"),
+	},
+}
+
+// snapshotSchema describes the tables, columns, indexes, constraints, functions, views, enums
+// and triggers of the database behind db. Objects owned by extensions and the tables in excludedTables are left out.
+func snapshotSchema(ctx context.Context, db DBTX, excludedTables []string) (schemaSnapshot, error) {
+	if excludedTables == nil {
+		excludedTables = []string{}
+	}
+
+	var snapshot schemaSnapshot
+	for _, q := range schemaQueries {
+		var args []any
+		if strings.Contains(q.query, "$1") {
+			args = append(args, excludedTables)
+		}
+		rows, err := db.Query(ctx, q.query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", q.kind, err)
+		}
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan %s: %w", q.kind, err)
+			}
+			snapshot = append(snapshot, line)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", q.kind, err)
+		}
+	}
+
+	slices.Sort(snapshot)
+	return snapshot, nil
+}
+
+func (s schemaSnapshot) String() string {
+	if len(s) == 0 {
+		return ""
+	}
+	return strings.Join(s, "\n") + "\n"
+}
+
+// diffSchemas lists the lines only in want (prefixed "- ") and only in got (prefixed "+ ").
+// It returns an empty string when the snapshots match.
+func diffSchemas(want, got schemaSnapshot) string {
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(want) || j < len(got) {
+		switch {
+		case j >= len(got) || (i < len(want) && want[i] < got[j]):
+			diff.WriteString("- " + want[i] + "\n")
+			i++
+		case i >= len(want) || got[j] < want[i]:
+			diff.WriteString("+ " + got[j] + "\n")
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return diff.String()
+}
@@ -0,0 +1,370 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ScriptError reports a failed SQL script statement with its file and line.
+// It matches ErrScriptFailed with errors.Is and unwraps to the underlying error.
+type ScriptError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("%v: %s:%d: %v", ErrScriptFailed, e.File, e.Line, e.Err)
+}
+
+// Is reports whether target is ErrScriptFailed
+func (e *ScriptError) Is(target error) bool {
+	return target == ErrScriptFailed
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// scriptFile is a resolved SQL script
+type scriptFile struct {
+	name    string
+	content string
+}
+
+// sqlStatement is a single statement split from a script
+type sqlStatement struct {
+	sql      string
+	line     int     // line of the statement's first token
+	copyData *string // rows for COPY ... FROM STDIN
+}
+
+// copyFromStdinPattern matches COPY statements whose data follows inline in the script
+var copyFromStdinPattern = regexp.MustCompile(`(?is)^COPY\b.*\bFROM\s+STDIN\b`)
+
+// resolveScripts expands paths and globs, in order, into script files.
+// Patterns are resolved in fsys when it is set, otherwise on the host file system.
+// A pattern that matches nothing is an error; a file matched twice runs once.
+func resolveScripts(fsys fs.FS, patterns []string) ([]scriptFile, error) {
+	var files []scriptFile
+	seen := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		var matches []string
+		var err error
+		if fsys != nil {
+			matches, err = fs.Glob(fsys, pattern)
+		} else {
+			matches, err = filepath.Glob(pattern)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid script pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("script pattern %q matched no files", pattern)
+		}
+		slices.Sort(matches)
+
+		for _, name := range matches {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			var content []byte
+			if fsys != nil {
+				content, err = fs.ReadFile(fsys, name)
+			} else {
+				content, err = os.ReadFile(name) // #nosec G304 -- paths come from the test configuration
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read script %s: %w", name, err)
+			}
+			files = append(files, scriptFile{name: name, content: string(content)})
+		}
+	}
+
+	return files, nil
+}
+
+// runScriptPatterns resolves patterns and runs the resulting scripts on one connection from pool
+func runScriptPatterns(ctx context.Context, pool *pgxpool.Pool, fsys fs.FS, patterns []string) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	files, err := resolveScripts(fsys, patterns)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrScriptFailed, err)
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseConnFailed, err)
+	}
+	defer conn.Release()
+
+	return runScripts(ctx, conn.Conn(), files)
+}
+
+// runScripts executes every statement of every file on conn, stopping at the first error
+func runScripts(ctx context.Context, conn *pgx.Conn, files []scriptFile) error {
+	for _, file := range files {
+		statements, err := splitSQL(file.content)
+		if err != nil {
+			var scriptErr *ScriptError
+			if errors.As(err, &scriptErr) {
+				scriptErr.File = file.name
+			}
+			return err
+		}
+
+		if err := execStatements(ctx, conn, file.name, statements); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// execStatements executes statements from file on conn, stopping at the first error
+func execStatements(ctx context.Context, conn *pgx.Conn, file string, statements []sqlStatement) error {
+	for _, stmt := range statements {
+		var err error
+		if stmt.copyData != nil {
+			_, err = conn.PgConn().CopyFrom(ctx, strings.NewReader(*stmt.copyData), stmt.sql)
+		} else {
+			_, err = conn.Exec(ctx, stmt.sql)
+		}
+		if err != nil {
+			return &ScriptError{File: file, Line: stmt.errorLine(err), Err: err}
+		}
+	}
+
+	return nil
+}
+
+// errorLine narrows the statement's line to the position PostgreSQL reported, if any
+func (s sqlStatement) errorLine(err error) int {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Position <= 0 {
+		return s.line
+	}
+
+	// Position is a 1-based character (not byte) offset into the statement
+	line := s.line
+	chars := 0
+	for _, r := range s.sql {
+		chars++
+		if chars >= int(pgErr.Position) {
+			break
+		}
+		if r == '\n' {
+			line++
+		}
+	}
+	return line
+}
+
+// splitSQL splits a script into statements on top-level semicolons.
+// It understands quoted strings and identifiers, backslash escapes in E-strings, dollar quoting, line and
+// (nested) block comments, and the inline data that follows COPY ... FROM STDIN up to "\.".
+func splitSQL(src string) ([]sqlStatement, error) {
+	var statements []sqlStatement
+
+	line := 1
+	start, startLine := 0, 0 // offset and line of the current statement's first token; 0 means none yet
+	begin := func(i int) {
+		if startLine == 0 {
+			start, startLine = i, line
+		}
+	}
+	unterminated := func(what string, atLine int) error {
+		return &ScriptError{Line: atLine, Err: fmt.Errorf("unterminated %s", what)}
+	}
+
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+
+		case c == '-' && strings.HasPrefix(src[i:], "--"):
+			if nl := strings.IndexByte(src[i:], '\n'); nl >= 0 {
+				i += nl
+			} else {
+				i = len(src)
+			}
+
+		case c == '/' && strings.HasPrefix(src[i:], "/*"):
+			openLine, depth := line, 0
+			for {
+				switch {
+				case i >= len(src):
+					return nil, unterminated("block comment", openLine)
+				case strings.HasPrefix(src[i:], "/*"):
+					depth++
+					i += 2
+					continue
+				case strings.HasPrefix(src[i:], "*/"):
+					depth--
+					i += 2
+				case src[i] == '\n':
+					line++
+					i++
+				default:
+					i++
+				}
+				if depth == 0 {
+					break
+				}
+			}
+
+		case c == '\'' || c == '"':
+			begin(i)
+			openLine := line
+			escapes := c == '\'' && i > 0 && (src[i-1] == 'E' || src[i-1] == 'e') && (i < 2 || !isIdentByte(src[i-2]))
+			i++
+			for {
+				if i >= len(src) {
+					return nil, unterminated("quoted string", openLine)
+				}
+				switch {
+				case escapes && src[i] == '\\' && i+1 < len(src):
+					if src[i+1] == '\n' {
+						line++
+					}
+					i += 2
+					continue
+				case src[i] == c && i+1 < len(src) && src[i+1] == c:
+					i += 2
+					continue
+				case src[i] == '\n':
+					line++
+				}
+				i++
+				if src[i-1] == c {
+					break
+				}
+			}
+
+		case c == '$':
+			begin(i)
+			if tag, ok := dollarQuoteTag(src, i); ok {
+				end := strings.Index(src[i+len(tag):], tag)
+				if end < 0 {
+					return nil, unterminated("dollar-quoted string "+tag, line)
+				}
+				body := src[i : i+len(tag)+end+len(tag)]
+				line += strings.Count(body, "\n")
+				i += len(body)
+			} else {
+				i++
+			}
+
+		case c == ';':
+			if startLine != 0 {
+				stmt := sqlStatement{sql: strings.TrimRightFunc(src[start:i], unicode.IsSpace), line: startLine}
+				i++
+				if copyFromStdinPattern.MatchString(stmt.sql) {
+					var data string
+					data, i, line = readCopyData(src, i, line)
+					stmt.copyData = &data
+				}
+				statements = append(statements, stmt)
+				startLine = 0
+			} else {
+				i++
+			}
+
+		default:
+			r, size := utf8.DecodeRuneInString(src[i:])
+			if !unicode.IsSpace(r) {
+				begin(i)
+			}
+			i += size
+		}
+	}
+
+	if startLine != 0 {
+		if sql := strings.TrimRightFunc(src[start:], unicode.IsSpace); sql != "" {
+			statements = append(statements, sqlStatement{sql: sql, line: startLine})
+		}
+	}
+
+	return statements, nil
+}
+
+// dollarQuoteTag returns the $tag$ opening a dollar-quoted string at src[i], if there is one.
+// Positional parameters ($1) and dollar signs inside identifiers are not dollar quotes.
+func dollarQuoteTag(src string, i int) (string, bool) {
+	if i > 0 && isIdentByte(src[i-1]) {
+		return "", false
+	}
+
+	j := i + 1
+	for j < len(src) && isIdentByte(src[j]) && src[j] != '$' {
+		j++
+	}
+	if j >= len(src) || src[j] != '$' {
+		return "", false
+	}
+	if j > i+1 && src[i+1] >= '0' && src[i+1] <= '9' {
+		return "", false
+	}
+
+	return src[i : j+1], true
+}
+
+// readCopyData collects the lines after a COPY ... FROM STDIN statement up to a "\." line or the end of src.
+// It returns the data and the offset and line number following it.
+func readCopyData(src string, i, line int) (string, int, int) {
+	// The data starts on the line after the statement
+	nl := strings.IndexByte(src[i:], '\n')
+	if nl < 0 {
+		return "", len(src), line
+	}
+	i += nl + 1
+	line++
+
+	var data strings.Builder
+	for i < len(src) {
+		end := strings.IndexByte(src[i:], '\n')
+		next := i + end + 1
+		if end < 0 {
+			end, next = len(src)-i, len(src)
+		}
+		row := src[i : i+end]
+		i = next
+		line++
+
+		if strings.TrimRight(row, "\r") == `\.` {
+			break
+		}
+		data.WriteString(row)
+		data.WriteByte('\n')
+	}
+
+	return data.String(), i, line
+}
+
+// isIdentByte reports whether b can appear in an unquoted identifier
+func isIdentByte(b byte) bool {
+	return b == '_' || b == '$' || b >= 0x80 ||
+		('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
@@ -0,0 +1,75 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestScripts_InitAndSeed(t *testing.T) {
+	migrationsDir := filepath.Join(t.TempDir(), "migrations")
+	if err := os.MkdirAll(migrationsDir, 0o755); err != nil {
+		t.Fatalf("Failed to create migrations directory: %v", err)
+	}
+	up := "CREATE TABLE countries (code TEXT PRIMARY KEY, name TEXT NOT NULL);"
+	if err := os.WriteFile(filepath.Join(migrationsDir, "001_create_countries.up.sql"), []byte(up), 0o644); err != nil {
+		t.Fatalf("Failed to write migration file: %v", err)
+	}
+
+	fsys := fstest.MapFS{
+		"init/01_functions.sql": {Data: []byte(`
+CREATE FUNCTION normalise_code(code TEXT) RETURNS TEXT AS $$
+BEGIN
+  RETURN upper(trim(code));
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+`)},
+		"seed/01_countries.sql": {Data: []byte("COPY countries (code, name) FROM STDIN;\nGB\tUnited Kingdom\nNL\tNetherlands\n\\.\nUPDATE countries SET code = normalise_code(code);\n")},
+	}
+
+	tc := New(t,
+		WithScriptsFS(fsys),
+		WithInitScripts("init/*.sql"),
+		WithMigrations(migrationsDir),
+		WithSeedScripts("seed/*.sql"),
+	)
+
+	var count int
+	if err := tc.Pool.QueryRow(context.Background(), "SELECT count(*) FROM countries").Scan(&count); err != nil {
+		t.Fatalf("Failed to count countries: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 seeded countries, got %d", count)
+	}
+}
+
+func TestScripts_ErrorReportsLine(t *testing.T) {
+	if skip, msg := SkipIfDockerUnavailable(); skip {
+		t.Skip(msg)
+	}
+
+	config := DefaultPostgreSQLConfig()
+	config.ScriptsFS = fstest.MapFS{
+		"seed.sql": {Data: []byte("SELECT 1;\n\nSELECT *\nFROM missing_table;\n")},
+	}
+	config.SeedScripts = []string{"seed.sql"}
+
+	tc, err := StartPostgreSQLContainer(context.Background(), config)
+	if err == nil {
+		tc.Close()
+		t.Fatal("Expected error for failing seed script")
+	}
+
+	var scriptErr *ScriptError
+	if !errors.As(err, &scriptErr) {
+		t.Fatalf("Expected ScriptError, got %v", err)
+	}
+	if scriptErr.File != "seed.sql" || scriptErr.Line != 4 {
+		t.Errorf("Expected error at seed.sql:4, got %s:%d", scriptErr.File, scriptErr.Line)
+	}
+}
@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestSplitSQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		want  []string
+		lines []int
+	}{
+		{
+			name:  "multiple statements",
+			src:   "CREATE TABLE a (id INT);\nINSERT INTO a VALUES (1);\n",
+			want:  []string{"CREATE TABLE a (id INT)", "INSERT INTO a VALUES (1)"},
+			lines: []int{1, 2},
+		},
+		{
+			name:  "semicolons in strings and identifiers",
+			src:   "INSERT INTO \"a;b\" VALUES ('x;y', 'it''s; fine');\nSELECT E'\\';';",
+			want:  []string{"INSERT INTO \"a;b\" VALUES ('x;y', 'it''s; fine')", "SELECT E'\\';'"},
+			lines: []int{1, 2},
+		},
+		{
+			name: "dollar-quoted function body",
+			src: `-- reference data helpers
+CREATE FUNCTION touch() RETURNS trigger AS $body$
+BEGIN
+  NEW.updated_at := now(); -- keep; going
+  RETURN NEW;
+END;
+$body$ LANGUAGE plpgsql;
+
+DO $$ BEGIN PERFORM 1; END $$;`,
+			want: []string{
+				"CREATE FUNCTION touch() RETURNS trigger AS $body$\nBEGIN\n  NEW.updated_at := now(); -- keep; going\n  RETURN NEW;\nEND;\n$body$ LANGUAGE plpgsql",
+				"DO $$ BEGIN PERFORM 1; END $$",
+			},
+			lines: []int{2, 9},
+		},
+		{
+			name:  "comments are skipped",
+			src:   "/* header; /* nested; */ still comment */\n-- only a comment;\n\nSELECT 1; -- trailing\n",
+			want:  []string{"SELECT 1"},
+			lines: []int{4},
+		},
+		{
+			name:  "positional parameters are not dollar quotes",
+			src:   "PREPARE q AS SELECT $1;\nSELECT 2",
+			want:  []string{"PREPARE q AS SELECT $1", "SELECT 2"},
+			lines: []int{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements, err := splitSQL(tt.src)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(statements) != len(tt.want) {
+				t.Fatalf("Expected %d statements, got %d: %+v", len(tt.want), len(statements), statements)
+			}
+			for i, stmt := range statements {
+				if stmt.sql != tt.want[i] {
+					t.Errorf("Statement %d = %q, want %q", i, stmt.sql, tt.want[i])
+				}
+				if stmt.line != tt.lines[i] {
+					t.Errorf("Statement %d line = %d, want %d", i, stmt.line, tt.lines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSplitSQL_CopyFromStdin(t *testing.T) {
+	src := "CREATE TABLE countries (code TEXT, name TEXT);\n" +
+		"COPY countries (code, name) FROM stdin;\n" +
+		"GB\tUnited Kingdom\n" +
+		"NL\tNetherlands; the\n" +
+		"\\.\n" +
+		"SELECT count(*) FROM countries;\n"
+
+	statements, err := splitSQL(src)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(statements) != 3 {
+		t.Fatalf("Expected 3 statements, got %d", len(statements))
+	}
+
+	copyStmt := statements[1]
+	if copyStmt.copyData == nil {
+		t.Fatal("Expected COPY statement to carry data")
+	}
+	if want := "GB\tUnited Kingdom\nNL\tNetherlands; the\n"; *copyStmt.copyData != want {
+		t.Errorf("Expected COPY data %q, got %q", want, *copyStmt.copyData)
+	}
+	if statements[2].line != 6 {
+		t.Errorf("Expected statement after COPY data on line 6, got %d", statements[2].line)
+	}
+}
+
+func TestSplitSQL_Unterminated(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+	}{
+		{name: "string", src: "SELECT 1;\nSELECT 'oops;\n", line: 2},
+		{name: "dollar quote", src: "\n\nDO $fn$ BEGIN END;", line: 3},
+		{name: "block comment", src: "SELECT 1; /* never closed", line: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := splitSQL(tt.src)
+			var scriptErr *ScriptError
+			if !errors.As(err, &scriptErr) {
+				t.Fatalf("Expected ScriptError, got %v", err)
+			}
+			if scriptErr.Line != tt.line {
+				t.Errorf("Expected line %d, got %d", tt.line, scriptErr.Line)
+			}
+		})
+	}
+}
+
+func TestSQLStatement_ErrorLine(t *testing.T) {
+	stmt := sqlStatement{sql: "INSERT INTO flags\nVALUES ('é', bogus)", line: 10}
+
+	// Position counts characters, so the multi-byte rune before "bogus" must not shift it
+	position := utf8.RuneCountInString(stmt.sql[:strings.Index(stmt.sql, "bogus")]) + 1
+	pgErr := &pgconn.PgError{Position: int32(position)}
+	if got := stmt.errorLine(pgErr); got != 11 {
+		t.Errorf("Expected error on line 11, got %d", got)
+	}
+	if got := stmt.errorLine(errors.New("connection reset")); got != 10 {
+		t.Errorf("Expected statement line 10 without a position, got %d", got)
+	}
+}
+
+func TestResolveScripts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"seed/02_flags.sql":     {Data: []byte("SELECT 2;")},
+		"seed/01_countries.sql": {Data: []byte("SELECT 1;")},
+		"init/schema.sql":       {Data: []byte("SELECT 0;")},
+	}
+
+	files, err := resolveScripts(fsys, []string{"init/schema.sql", "seed/*.sql", "seed/01_countries.sql"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var names []string
+	for _, f := range files {
+		names = append(names, f.name)
+	}
+	if want := "init/schema.sql,seed/01_countries.sql,seed/02_flags.sql"; strings.Join(names, ",") != want {
+		t.Errorf("Expected files %s, got %s", want, strings.Join(names, ","))
+	}
+
+	if _, err := resolveScripts(fsys, []string{"missing/*.sql"}); err == nil {
+		t.Error("Expected error for pattern that matches no files")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "seed.sql"), []byte("SELECT 1;"), 0o644); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+	files, err = resolveScripts(nil, []string{filepath.Join(dir, "*.sql")})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(files) != 1 || files[0].content != "SELECT 1;" {
+		t.Errorf("Expected one host script, got %+v", files)
+	}
+}
+
+func TestScriptOptions(t *testing.T) {
+	config, err := buildConfig(WithInitScripts("init/*.sql"), WithSeedScripts("seed/*.sql"), WithScriptsFS(fstest.MapFS{}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(config.InitScripts) != 1 || len(config.SeedScripts) != 1 || config.ScriptsFS == nil {
+		t.Errorf("Expected scripts to be configured, got %+v", config)
+	}
+
+	if _, err := buildConfig(WithSeedScripts("seed/[.sql")); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for malformed glob, got %v", err)
+	}
+}
+
+func TestScriptError(t *testing.T) {
+	err := &ScriptError{File: "seed/flags.sql", Line: 7, Err: errors.New("syntax error")}
+
+	if want := "SQL script failed: seed/flags.sql:7: syntax error"; err.Error() != want {
+		t.Errorf("Error message = %v, want %v", err.Error(), want)
+	}
+	if !errors.Is(err, ErrScriptFailed) {
+		t.Error("Expected ScriptError to match ErrScriptFailed")
+	}
+}
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// PerformanceProfile selects the server settings the container is started with
+type PerformanceProfile string
+
+const (
+	// ProfileDurable starts PostgreSQL with its durable defaults (fsync, synchronous commit, full page writes)
+	ProfileDurable PerformanceProfile = "durable"
+	// ProfileFast trades durability for speed and keeps the data directory on a tmpfs
+	ProfileFast PerformanceProfile = "fast"
+	// ProfileCustom starts PostgreSQL with only the settings in PostgreSQLConfig.ServerSettings
+	ProfileCustom PerformanceProfile = "custom"
+)
+
+const (
+	// tmpfsDataDir is mounted as a tmpfs by ProfileFast
+	tmpfsDataDir = "/var/lib/postgresql/data"
+	// tmpfsPGDATA is a subdirectory so initdb finds an empty directory it can own
+	tmpfsPGDATA = tmpfsDataDir + "/pgdata"
+)
+
+// profileSettings are the GUCs each profile applies before ServerSettings
+var profileSettings = map[PerformanceProfile]map[string]string{
+	ProfileDurable: {
+		"fsync":              "on",
+		"synchronous_commit": "on",
+		"full_page_writes":   "on",
+	},
+	ProfileFast: {
+		"fsync":              "off",
+		"synchronous_commit": "off",
+		"full_page_writes":   "off",
+	},
+	ProfileCustom: {},
+}
+
+// settingNamePattern matches PostgreSQL configuration parameter names, including custom "ext.name" ones
+var settingNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+
+// serverSettings returns the GUCs passed to the server: the profile's settings overridden by ServerSettings.
+// It returns nil when neither a profile nor settings are configured, keeping the module's default command.
+func (c *PostgreSQLConfig) serverSettings() map[string]string {
+	if c.PerformanceProfile == "" && len(c.ServerSettings) == 0 {
+		return nil
+	}
+
+	settings := maps.Clone(profileSettings[c.PerformanceProfile])
+	if settings == nil {
+		// ServerSettings without a profile extend the module default of fsync=off
+		settings = map[string]string{"fsync": "off"}
+	}
+	maps.Copy(settings, c.ServerSettings)
+	return settings
+}
+
+// settingsOptions returns the customizers that apply the profile and server settings
+func settingsOptions(config *PostgreSQLConfig) []testcontainers.ContainerCustomizer {
+	settings := config.serverSettings()
+	if settings == nil {
+		return nil
+	}
+
+	cmd := []string{"postgres"}
+	for _, name := range slices.Sorted(maps.Keys(settings)) {
+		cmd = append(cmd, "-c", name+"="+settings[name])
+	}
+	opts := []testcontainers.ContainerCustomizer{testcontainers.WithCmd(cmd...)}
+
+	// A snapshot must be committed to an image, which a tmpfs data directory is not part of
+	if config.PerformanceProfile == ProfileFast && !config.SnapshotCache {
+		opts = append(opts,
+			testcontainers.WithTmpfs(map[string]string{tmpfsDataDir: "rw"}),
+			testcontainers.WithEnv(map[string]string{"PGDATA": tmpfsPGDATA}),
+		)
+	}
+
+	return opts
+}
+
+// validateProfile checks that profile is empty or one of the known profiles
+func validateProfile(profile PerformanceProfile) error {
+	if _, ok := profileSettings[profile]; profile != "" && !ok {
+		return &ConfigError{Field: "PerformanceProfile", Value: string(profile), Reason: "is not a known performance profile"}
+	}
+	return nil
+}
+
+// validateSettingNames checks the names of server settings
+func validateSettingNames(settings map[string]string) error {
+	for _, name := range slices.Sorted(maps.Keys(settings)) {
+		if !settingNamePattern.MatchString(name) {
+			return &ConfigError{Field: "ServerSettings", Value: name, Reason: "is not a valid setting name"}
+		}
+	}
+	return nil
+}
+
+// Settings returns the effective value of each named setting from pg_settings.
+// With no names it returns every setting. Values are in the setting's base unit, as in pg_settings.setting.
+func (tc *PostgreSQLTestContainer) Settings(ctx context.Context, names ...string) (map[string]string, error) {
+	if names == nil {
+		names = []string{} // A nil slice would be sent as NULL
+	}
+
+	rows, err := tc.Pool.Query(ctx, `
+		SELECT name, setting
+		FROM pg_settings
+		WHERE cardinality($1::text[]) = 0 OR name = ANY($1)
+	`, names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pg_settings: %w", err)
+	}
+	defer rows.Close()
+
+	settings := make(map[string]string)
+	for rows.Next() {
+		var name, setting string
+		if err := rows.Scan(&name, &setting); err != nil {
+			return nil, fmt.Errorf("failed to scan setting: %w", err)
+		}
+		settings[name] = setting
+	}
+
+	return settings, rows.Err()
+}
@@ -0,0 +1,48 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPerformanceProfile_Fast(t *testing.T) {
+	tc := New(t,
+		WithPerformanceProfile(ProfileFast),
+		WithServerSettings(map[string]string{"max_connections": "50"}),
+	)
+
+	settings, err := tc.Settings(context.Background(), "fsync", "synchronous_commit", "full_page_writes", "max_connections", "data_directory")
+	if err != nil {
+		t.Fatalf("Failed to read settings: %v", err)
+	}
+
+	want := map[string]string{
+		"fsync":              "off",
+		"synchronous_commit": "off",
+		"full_page_writes":   "off",
+		"max_connections":    "50",
+		"data_directory":     tmpfsPGDATA,
+	}
+	for name, value := range want {
+		if settings[name] != value {
+			t.Errorf("Expected %s to be %s, got %s", name, value, settings[name])
+		}
+	}
+}
+
+func TestPerformanceProfile_Durable(t *testing.T) {
+	tc := New(t, WithPerformanceProfile(ProfileDurable))
+
+	settings, err := tc.Settings(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read settings: %v", err)
+	}
+	if settings["fsync"] != "on" {
+		t.Errorf("Expected fsync to be on, got %s", settings["fsync"])
+	}
+	if len(settings) < 100 {
+		t.Errorf("Expected all settings without names, got %d", len(settings))
+	}
+}
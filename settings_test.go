@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestPostgreSQLConfig_ServerSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  PerformanceProfile
+		settings map[string]string
+		want     map[string]string
+	}{
+		{
+			name: "module default",
+			want: nil,
+		},
+		{
+			name:    "fast",
+			profile: ProfileFast,
+			want:    map[string]string{"fsync": "off", "synchronous_commit": "off", "full_page_writes": "off"},
+		},
+		{
+			name:     "durable with override",
+			profile:  ProfileDurable,
+			settings: map[string]string{"synchronous_commit": "local", "max_connections": "200"},
+			want:     map[string]string{"fsync": "on", "synchronous_commit": "local", "full_page_writes": "on", "max_connections": "200"},
+		},
+		{
+			name:     "custom",
+			profile:  ProfileCustom,
+			settings: map[string]string{"work_mem": "64MB"},
+			want:     map[string]string{"work_mem": "64MB"},
+		},
+		{
+			name:     "settings without profile keep fsync off",
+			settings: map[string]string{"log_statement": "all"},
+			want:     map[string]string{"fsync": "off", "log_statement": "all"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &PostgreSQLConfig{PerformanceProfile: tt.profile, ServerSettings: tt.settings}
+			if got := config.serverSettings(); !maps.Equal(got, tt.want) {
+				t.Errorf("Expected settings to be %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSettingsOptions(t *testing.T) {
+	if opts := settingsOptions(DefaultPostgreSQLConfig()); len(opts) != 0 {
+		t.Errorf("Expected no customizers for the default config, got %d", len(opts))
+	}
+
+	config := DefaultPostgreSQLConfig()
+	config.PerformanceProfile = ProfileFast
+
+	req := testcontainers.GenericContainerRequest{}
+	for _, opt := range settingsOptions(config) {
+		if err := opt.Customize(&req); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	wantCmd := []string{"postgres", "-c", "fsync=off", "-c", "full_page_writes=off", "-c", "synchronous_commit=off"}
+	if !slices.Equal(req.Cmd, wantCmd) {
+		t.Errorf("Expected cmd to be %v, got %v", wantCmd, req.Cmd)
+	}
+	if _, ok := req.Tmpfs[tmpfsDataDir]; !ok {
+		t.Errorf("Expected a tmpfs at %s, got %v", tmpfsDataDir, req.Tmpfs)
+	}
+	if req.Env["PGDATA"] != tmpfsPGDATA {
+		t.Errorf("Expected PGDATA to be %s, got %s", tmpfsPGDATA, req.Env["PGDATA"])
+	}
+}
+
+func TestPerformanceProfileOptions(t *testing.T) {
+	base := map[string]string{"work_mem": "4MB"}
+	config, err := buildConfig(
+		WithPerformanceProfile(ProfileCustom),
+		WithServerSettings(base),
+		WithServerSettings(map[string]string{"work_mem": "64MB", "pg_stat_statements.track": "all"}),
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.ServerSettings["work_mem"] != "64MB" {
+		t.Errorf("Expected later settings to win, got %s", config.ServerSettings["work_mem"])
+	}
+	if base["work_mem"] != "4MB" {
+		t.Error("Expected the caller's map to be left unchanged")
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "unknown profile", opts: []Option{WithPerformanceProfile("turbo")}},
+		{name: "custom without settings", opts: []Option{WithPerformanceProfile(ProfileCustom)}},
+		{name: "invalid setting name", opts: []Option{WithServerSettings(map[string]string{"fsync=off -c x": "on"})}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := buildConfig(tt.opts...); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+}
+
+func TestContainerFingerprint_Settings(t *testing.T) {
+	fast := DefaultPostgreSQLConfig()
+	fast.PerformanceProfile = ProfileFast
+
+	if containerFingerprint(DefaultPostgreSQLConfig()) == containerFingerprint(fast) {
+		t.Error("Expected containers with different profiles to have different fingerprints")
+	}
+}
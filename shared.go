@@ -0,0 +1,322 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// sharedContainers is the process-wide registry used by AcquireSharedContainer
+var sharedContainers = newSharedRegistry(StartPostgreSQLContainerWithCheck)
+
+// sharedEntry is a single shared container and the number of callers using it
+type sharedEntry struct {
+	ready chan struct{} // closed once the container has started (or failed to)
+	tc    *PostgreSQLTestContainer
+	err   error
+	refs  int
+}
+
+// sharedRegistry hands out one container per configuration fingerprint
+type sharedRegistry struct {
+	mu      sync.Mutex
+	entries map[string]*sharedEntry
+	pinned  int // number of RunWithSharedContainers calls in progress
+	start   func(ctx context.Context, config *PostgreSQLConfig) (*PostgreSQLTestContainer, error)
+}
+
+func newSharedRegistry(start func(context.Context, *PostgreSQLConfig) (*PostgreSQLTestContainer, error)) *sharedRegistry {
+	return &sharedRegistry{
+		entries: make(map[string]*sharedEntry),
+		start:   start,
+	}
+}
+
+// AcquireSharedContainer returns the process-wide container for config, starting it on first use.
+// Concurrent callers with an equivalent configuration receive the same instance.
+//
+// Every successful call must be paired with ReleaseSharedContainer. Do not call Close on a
+// shared container directly; it is terminated once the last user releases it, or when
+// RunWithSharedContainers returns.
+func AcquireSharedContainer(ctx context.Context, config *PostgreSQLConfig) (*PostgreSQLTestContainer, error) {
+	return sharedContainers.acquire(ctx, config)
+}
+
+// ReleaseSharedContainer gives up one reference to a container returned by AcquireSharedContainer
+func ReleaseSharedContainer(tc *PostgreSQLTestContainer) error {
+	return sharedContainers.release(tc)
+}
+
+// CloseSharedContainers terminates every shared container regardless of outstanding references
+func CloseSharedContainers() error {
+	return sharedContainers.closeAll(nil)
+}
+
+// RunWithSharedContainers runs the tests and terminates all shared containers afterwards.
+// While the tests run, shared containers stay alive even when no test holds a reference,
+// so sequential tests reuse the same container. Use it from TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(postgres.RunWithSharedContainers(m))
+//	}
+func RunWithSharedContainers(m *testing.M) int {
+	sharedContainers.pin()
+	code := m.Run()
+	sharedContainers.unpin()
+
+	// Each failure is reported through the logger of the container's configuration
+	_ = sharedContainers.closeAll(func(tc *PostgreSQLTestContainer, err error) {
+		tc.logf("Warning: failed to cleanup shared PostgreSQL container: %v", err)
+	})
+
+	return code
+}
+
+// SharedContainer acquires the shared container for the calling test and releases it via t.Cleanup.
+// Like New, it skips the test when Docker is unavailable and fails it on startup errors.
+func SharedContainer(t testing.TB, opts ...Option) *PostgreSQLTestContainer {
+	t.Helper()
+
+	config, err := buildConfig(opts...)
+	if err != nil {
+		t.Fatalf("Invalid PostgreSQL configuration: %v", err)
+	}
+
+	tc, err := AcquireSharedContainer(context.Background(), config)
+	if errors.Is(err, ErrDockerNotAvailable) {
+		t.Skipf("Skipping test: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("Failed to start shared PostgreSQL container: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := ReleaseSharedContainer(tc); err != nil {
+			t.Logf("Warning: failed to release shared PostgreSQL container: %v", err)
+		}
+	})
+
+	return tc
+}
+
+func (r *sharedRegistry) acquire(ctx context.Context, config *PostgreSQLConfig) (*PostgreSQLTestContainer, error) {
+	if config == nil {
+		config = DefaultPostgreSQLConfig()
+	}
+	key := configFingerprint(config)
+
+	r.mu.Lock()
+	entry, ok := r.entries[key]
+	if !ok {
+		entry = &sharedEntry{ready: make(chan struct{})}
+		r.entries[key] = entry
+	}
+	entry.refs++
+	r.mu.Unlock()
+
+	if !ok {
+		// The container outlives the caller, so it must not inherit its cancellation
+		entry.tc, entry.err = r.start(context.WithoutCancel(ctx), config)
+		if entry.err != nil {
+			r.mu.Lock()
+			delete(r.entries, key)
+			r.mu.Unlock()
+		}
+		close(entry.ready)
+	}
+
+	select {
+	case <-entry.ready:
+	case <-ctx.Done():
+		return nil, r.abandon(key, entry, ctx.Err())
+	}
+
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	return entry.tc, nil
+}
+
+func (r *sharedRegistry) release(tc *PostgreSQLTestContainer) error {
+	r.mu.Lock()
+	for key, entry := range r.entries {
+		if entry.tc != tc {
+			continue
+		}
+
+		entry.refs--
+		if entry.refs > 0 || r.pinned > 0 {
+			r.mu.Unlock()
+			return nil
+		}
+
+		delete(r.entries, key)
+		r.mu.Unlock()
+		return tc.Close()
+	}
+	r.mu.Unlock()
+
+	return errors.New("container was not acquired with AcquireSharedContainer")
+}
+
+// abandon gives up the reference of a caller that stopped waiting for the container. The caller
+// that starts the container holds a reference until it has started, so a count of zero means
+// the container is ready and nobody else uses it.
+func (r *sharedRegistry) abandon(key string, entry *sharedEntry, err error) error {
+	r.mu.Lock()
+	entry.refs--
+	if entry.refs > 0 || r.pinned > 0 {
+		r.mu.Unlock()
+		return err
+	}
+	if r.entries[key] == entry {
+		delete(r.entries, key)
+	}
+	r.mu.Unlock()
+
+	if entry.tc == nil {
+		return err
+	}
+	return errors.Join(err, entry.tc.Close())
+}
+
+func (r *sharedRegistry) pin() {
+	r.mu.Lock()
+	r.pinned++
+	r.mu.Unlock()
+}
+
+func (r *sharedRegistry) unpin() {
+	r.mu.Lock()
+	r.pinned--
+	r.mu.Unlock()
+}
+
+// closeAll terminates every container, passing each failure to report when it is non-nil
+func (r *sharedRegistry) closeAll(report func(tc *PostgreSQLTestContainer, err error)) error {
+	r.mu.Lock()
+	entries := r.entries
+	r.entries = make(map[string]*sharedEntry)
+	r.mu.Unlock()
+
+	var errs []error
+	for _, entry := range entries {
+		<-entry.ready
+		if entry.tc == nil {
+			continue
+		}
+		if err := entry.tc.Close(); err != nil {
+			if report != nil {
+				report(entry.tc, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// configFingerprint identifies configurations that can safely share a container.
+// Fields that do not affect the container (such as Logf) are ignored.
+func configFingerprint(config *PostgreSQLConfig) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|", containerFingerprint(config))
+	fmt.Fprintf(h, "%d|%d|%d|%d|%d|%d|", config.MaxConns, config.MinConns, config.MaxConnLife, config.MaxConnIdle, config.CloneMaxConns, config.StartupTimeout)
+	fmt.Fprintf(h, "%t|%q|%t|%q|", config.RunMigrations, config.MigrationsPath, config.ReuseContainer, config.imageFamily())
+	for _, ext := range config.Extensions {
+		fmt.Fprintf(h, "%q/%q/%q|", ext.Name, ext.Version, ext.Schema)
+	}
+	fmt.Fprintf(h, "%q|%q|%s|", config.InitScripts, config.SeedScripts, scriptsDigest(config))
+	fmt.Fprintf(h, "%s|%t|%s|%q|", migrationsFSDigest(config), config.SnapshotCache, cleaningFingerprint(config.Cleaning), config.externalDatabaseURL())
+	if config.Migrator != nil {
+		fmt.Fprintf(h, "%s|", migratorFingerprint(config.Migrator))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cleaningFingerprint describes clean options by value; regular expressions by their source
+func cleaningFingerprint(o CleanOptions) string {
+	patterns := make([]string, len(o.ExcludePatterns))
+	for i, pattern := range o.ExcludePatterns {
+		patterns[i] = pattern.String()
+	}
+	return fmt.Sprintf("%q/%t/%t/%q/%q/%q/%q", o.Mode, o.RestartIdentity, o.TrackDirtyTables,
+		o.Schemas, o.ExcludeTables, patterns, o.PreserveTables)
+}
+
+// migratorFingerprint describes a Migrator by value. The built-in migrators are described by
+// their engine and migration files; others by their type and String method if they have one,
+// or else their formatted value.
+func migratorFingerprint(m Migrator) string {
+	switch m := m.(type) {
+	case *golangMigrator:
+		if m.source.fsys == nil {
+			return fmt.Sprintf("golang-migrate:%q", m.source.path)
+		}
+		return "golang-migrate:" + fsDigest(m.source.fsys, m.source.dir)
+	case *gooseMigrator:
+		return "goose:" + fsDigest(m.fsys, m.dir)
+	case *sqlFilesMigrator:
+		return "sql-files:" + fsDigest(m.fsys, m.dir)
+	case fmt.Stringer:
+		return fmt.Sprintf("%T:%s", m, m.String())
+	}
+	return fmt.Sprintf("%T:%+v", m, m)
+}
+
+// fsDigest hashes the files in dir of fsys, since an fs.FS cannot be compared
+func fsDigest(fsys fs.FS, dir string) string {
+	digest, err := dirDigest(fsys, dir)
+	if err != nil {
+		return "error:" + err.Error()
+	}
+	return digest
+}
+
+// scriptsDigest hashes the contents of the configured scripts, since an fs.FS cannot be compared
+func scriptsDigest(config *PostgreSQLConfig) string {
+	if len(config.InitScripts) == 0 && len(config.SeedScripts) == 0 {
+		return ""
+	}
+
+	h := sha256.New()
+	for _, patterns := range [][]string{config.InitScripts, config.SeedScripts} {
+		files, err := resolveScripts(config.ScriptsFS, patterns)
+		if err != nil {
+			// Startup reports the error; the configuration must not match a working one
+			fmt.Fprintf(h, "error:%v|", err)
+			continue
+		}
+		for _, file := range files {
+			fmt.Fprintf(h, "%q=%q|", file.name, file.content)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// migrationsFSDigest hashes the migration files in MigrationsFS
+func migrationsFSDigest(config *PostgreSQLConfig) string {
+	if config.MigrationsFS == nil {
+		return ""
+	}
+
+	return fsDigest(config.MigrationsFS, config.migrationSource().dir)
+}
+
+// containerFingerprint covers only the settings baked into the container itself
+func containerFingerprint(config *PostgreSQLConfig) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q|%q|%q|%q|%q|", config.DatabaseName, config.Username, config.Password, config.imageReference(), config.PerformanceProfile)
+	settings := config.serverSettings()
+	for _, name := range slices.Sorted(maps.Keys(settings)) {
+		fmt.Fprintf(h, "%q=%q|", name, settings[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,26 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSharedContainer(t *testing.T) {
+	var first *PostgreSQLTestContainer
+
+	t.Run("first", func(t *testing.T) {
+		first = SharedContainer(t)
+		if err := first.Pool.Ping(context.Background()); err != nil {
+			t.Fatalf("Failed to ping database: %v", err)
+		}
+	})
+
+	t.Run("second", func(t *testing.T) {
+		second := SharedContainer(t)
+		if second != first {
+			t.Error("Expected the shared container to be reused across tests")
+		}
+	})
+}
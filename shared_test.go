@@ -0,0 +1,232 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// fakeStarter counts container starts without needing Docker
+func fakeStarter(starts *atomic.Int32) func(context.Context, *PostgreSQLConfig) (*PostgreSQLTestContainer, error) {
+	return func(ctx context.Context, config *PostgreSQLConfig) (*PostgreSQLTestContainer, error) {
+		starts.Add(1)
+		time.Sleep(10 * time.Millisecond) // Give concurrent callers a chance to race
+		return &PostgreSQLTestContainer{Context: ctx, DatabaseName: config.DatabaseName}, nil
+	}
+}
+
+func TestSharedRegistry_ConcurrentAcquire(t *testing.T) {
+	var starts atomic.Int32
+	r := newSharedRegistry(fakeStarter(&starts))
+
+	var wg sync.WaitGroup
+	results := make([]*PostgreSQLTestContainer, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tc, err := r.acquire(context.Background(), DefaultPostgreSQLConfig())
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			results[i] = tc
+		}(i)
+	}
+	wg.Wait()
+
+	if starts.Load() != 1 {
+		t.Errorf("Expected 1 container start, got %d", starts.Load())
+	}
+	for _, tc := range results {
+		if tc != results[0] {
+			t.Fatal("Expected all callers to receive the same container")
+		}
+	}
+}
+
+func TestSharedRegistry_DifferentConfigs(t *testing.T) {
+	var starts atomic.Int32
+	r := newSharedRegistry(fakeStarter(&starts))
+
+	other := DefaultPostgreSQLConfig()
+	other.DatabaseName = "otherdb"
+
+	tc1, _ := r.acquire(context.Background(), DefaultPostgreSQLConfig())
+	tc2, _ := r.acquire(context.Background(), other)
+
+	if tc1 == tc2 {
+		t.Error("Expected different configurations to get different containers")
+	}
+	if starts.Load() != 2 {
+		t.Errorf("Expected 2 container starts, got %d", starts.Load())
+	}
+}
+
+func TestSharedRegistry_ReleaseTerminatesLastUser(t *testing.T) {
+	var starts atomic.Int32
+	r := newSharedRegistry(fakeStarter(&starts))
+
+	tc1, _ := r.acquire(context.Background(), DefaultPostgreSQLConfig())
+	tc2, _ := r.acquire(context.Background(), DefaultPostgreSQLConfig())
+
+	if err := r.release(tc1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(r.entries) != 1 {
+		t.Errorf("Expected container to stay alive while referenced, got %d entries", len(r.entries))
+	}
+
+	if err := r.release(tc2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(r.entries) != 0 {
+		t.Errorf("Expected container to be removed after last release, got %d entries", len(r.entries))
+	}
+
+	// A new acquire starts a fresh container
+	if _, err := r.acquire(context.Background(), DefaultPostgreSQLConfig()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if starts.Load() != 2 {
+		t.Errorf("Expected 2 container starts, got %d", starts.Load())
+	}
+}
+
+func TestSharedRegistry_PinnedKeepsAlive(t *testing.T) {
+	var starts atomic.Int32
+	r := newSharedRegistry(fakeStarter(&starts))
+	r.pin()
+
+	tc, _ := r.acquire(context.Background(), DefaultPostgreSQLConfig())
+	if err := r.release(tc); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	again, _ := r.acquire(context.Background(), DefaultPostgreSQLConfig())
+	if again != tc {
+		t.Error("Expected pinned registry to reuse the released container")
+	}
+	if starts.Load() != 1 {
+		t.Errorf("Expected 1 container start, got %d", starts.Load())
+	}
+
+	r.unpin()
+	if err := r.closeAll(nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(r.entries) != 0 {
+		t.Errorf("Expected no entries after closeAll, got %d", len(r.entries))
+	}
+}
+
+func TestSharedRegistry_StartError(t *testing.T) {
+	startErr := errors.New("start failed")
+	r := newSharedRegistry(func(context.Context, *PostgreSQLConfig) (*PostgreSQLTestContainer, error) {
+		return nil, startErr
+	})
+
+	_, err := r.acquire(context.Background(), DefaultPostgreSQLConfig())
+	if !errors.Is(err, startErr) {
+		t.Errorf("Expected start error, got %v", err)
+	}
+	if len(r.entries) != 0 {
+		t.Errorf("Expected failed entry to be removed, got %d entries", len(r.entries))
+	}
+}
+
+func TestSharedRegistry_AbandonLastReference(t *testing.T) {
+	var starts atomic.Int32
+	r := newSharedRegistry(fakeStarter(&starts))
+
+	tc, _ := r.acquire(context.Background(), DefaultPostgreSQLConfig())
+	key := configFingerprint(DefaultPostgreSQLConfig())
+	entry := r.entries[key]
+
+	// A caller giving up the last reference closes the container instead of leaking it
+	err := r.abandon(key, entry, context.Canceled)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(r.entries) != 0 {
+		t.Errorf("Expected the entry to be removed, got %d entries", len(r.entries))
+	}
+	if entry.tc != tc {
+		t.Error("Expected the abandoned entry to hold the acquired container")
+	}
+}
+
+func TestSharedRegistry_AbandonWhileReferenced(t *testing.T) {
+	var starts atomic.Int32
+	r := newSharedRegistry(fakeStarter(&starts))
+
+	_, _ = r.acquire(context.Background(), DefaultPostgreSQLConfig())
+	_, _ = r.acquire(context.Background(), DefaultPostgreSQLConfig())
+	key := configFingerprint(DefaultPostgreSQLConfig())
+
+	if err := r.abandon(key, r.entries[key], context.Canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if entry := r.entries[key]; entry == nil || entry.refs != 1 {
+		t.Errorf("Expected the container to stay with 1 reference, got %+v", entry)
+	}
+}
+
+func TestSharedRegistry_ReleaseUnknown(t *testing.T) {
+	r := newSharedRegistry(nil)
+
+	if err := r.release(&PostgreSQLTestContainer{}); err == nil {
+		t.Error("Expected error when releasing an unknown container")
+	}
+}
+
+func TestConfigFingerprint(t *testing.T) {
+	a := DefaultPostgreSQLConfig()
+	b := DefaultPostgreSQLConfig()
+	b.Logf = t.Logf
+
+	if configFingerprint(a) != configFingerprint(b) {
+		t.Error("Expected Logf to be ignored by the fingerprint")
+	}
+
+	b.PostgreSQLVersion = "15-3.4"
+	if configFingerprint(a) == configFingerprint(b) {
+		t.Error("Expected different versions to produce different fingerprints")
+	}
+}
+
+func TestConfigFingerprint_EquivalentValues(t *testing.T) {
+	migrations := fstest.MapFS{"001_init.sql": {Data: []byte("CREATE TABLE t (id INT);")}}
+
+	build := func(dir string) *PostgreSQLConfig {
+		config := DefaultPostgreSQLConfig()
+		config.Cleaning.ExcludePatterns = []*regexp.Regexp{regexp.MustCompile(`^audit\.`)}
+		config.Migrator = NewSQLFilesMigrator(migrations, dir)
+		return config
+	}
+
+	// Separately compiled patterns and separately created migrators must not split containers
+	if configFingerprint(build(".")) != configFingerprint(build(".")) {
+		t.Error("Expected equivalent configurations to share a fingerprint")
+	}
+
+	other := build(".")
+	other.Cleaning.ExcludePatterns = []*regexp.Regexp{regexp.MustCompile(`^logs\.`)}
+	if configFingerprint(build(".")) == configFingerprint(other) {
+		t.Error("Expected different patterns to produce different fingerprints")
+	}
+
+	other = build(".")
+	other.Migrator = NewGooseMigrator(migrations, ".")
+	if configFingerprint(build(".")) == configFingerprint(other) {
+		t.Error("Expected different migration engines to produce different fingerprints")
+	}
+
+	if configFingerprint(build(".")) != configFingerprint(build("")) {
+		t.Error("Expected an empty directory to mean the root")
+	}
+}
@@ -0,0 +1,268 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+const (
+	// snapshotRepository is the local repository snapshot images are tagged in
+	snapshotRepository = "jp-testcontainers-postgres-snapshot"
+
+	// snapshotPGDATA is the data directory of snapshot containers. It sits outside the VOLUME
+	// of every supported image family, since docker commit does not capture volumes.
+	snapshotPGDATA = "/pgtc-snapshot/pgdata"
+
+	// SnapshotLabel is set on snapshot images to their cache key
+	SnapshotLabel = "org.jp-go-testcontainers-postgres.snapshot"
+
+	// SnapshotBaseImageLabel is set on snapshot images to the image they were built from
+	SnapshotBaseImageLabel = "org.jp-go-testcontainers-postgres.snapshot.base"
+
+	// testcontainersSessionLabel marks resources that Ryuk removes when the test session ends
+	testcontainersSessionLabel = "org.testcontainers.sessionId"
+)
+
+// snapshotDigester is implemented by migrators whose result can be cached in a snapshot image
+type snapshotDigester interface {
+	// migrationsDigest hashes every migration the migrator would apply
+	migrationsDigest() (string, error)
+}
+
+func (m *golangMigrator) migrationsDigest() (string, error) {
+	if m.source.fsys != nil {
+		return dirDigest(m.source.fsys, m.source.dir)
+	}
+
+	migrationsPath, err := resolveMigrationsPath(m.source.path, m.logger())
+	if err != nil {
+		return "", err
+	}
+	return dirDigest(os.DirFS(migrationsPath), ".")
+}
+
+func (m *gooseMigrator) migrationsDigest() (string, error) {
+	return dirDigest(m.fsys, m.dir)
+}
+
+func (m *sqlFilesMigrator) migrationsDigest() (string, error) {
+	return dirDigest(m.fsys, m.dir)
+}
+
+// dirDigest hashes the names and contents of every file below dir in fsys
+func dirDigest(fsys fs.FS, dir string) (string, error) {
+	h := sha256.New()
+	err := fs.WalkDir(fsys, dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%q=%q|", name, content)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash migrations in %s: %w", dir, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// snapshotKey identifies the prepared database built for config. It covers everything baked
+// into the data directory: the image, credentials, extensions, scripts and migrations.
+// Server settings are passed on the command line, so snapshots are shared across profiles.
+func snapshotKey(config *PostgreSQLConfig) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q|%q|%q|%q|", config.imageReference(), config.DatabaseName, config.Username, config.Password)
+	for _, ext := range config.Extensions {
+		fmt.Fprintf(h, "%q/%q/%q|", ext.Name, ext.Version, ext.Schema)
+	}
+	fmt.Fprintf(h, "%q|%q|%s|", config.InitScripts, config.SeedScripts, scriptsDigest(config))
+
+	if migrator := config.startupMigrator(); migrator != nil {
+		digester, ok := migrator.(snapshotDigester)
+		if !ok {
+			return "", &ConfigError{Field: "Migrator", Value: fmt.Sprintf("%T", migrator), Reason: "does not support SnapshotCache"}
+		}
+		digest, err := digester.migrationsDigest()
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrMigrationsFailed, err)
+		}
+		fmt.Fprintf(h, "%T|%s|", migrator, digest)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// snapshotTag returns the local image reference of the snapshot with key
+func snapshotTag(key string) string {
+	return snapshotRepository + ":" + key[:16]
+}
+
+// ensureSnapshot returns the snapshot image for config, building it on a cache miss
+func ensureSnapshot(ctx context.Context, config *PostgreSQLConfig) (string, error) {
+	key, err := snapshotKey(config)
+	if err != nil {
+		return "", err
+	}
+	tag := snapshotTag(key)
+
+	cli, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrDockerNotAvailable, err)
+	}
+	defer cli.Close()
+
+	if found, err := snapshotExists(ctx, cli, key, tag); err != nil || found {
+		return tag, err
+	}
+
+	// Another test process may be building the same snapshot
+	unlock, err := acquireHostLock(snapshotRepository + "-" + key[:16])
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	if found, err := snapshotExists(ctx, cli, key, tag); err != nil || found {
+		return tag, err
+	}
+
+	if err := buildSnapshot(ctx, cli, config, key, tag); err != nil {
+		return "", err
+	}
+
+	return tag, nil
+}
+
+// snapshotExists reports whether the snapshot image for key is tagged locally
+func snapshotExists(ctx context.Context, cli *testcontainers.DockerClient, key, tag string) (bool, error) {
+	images, err := cli.ImageList(ctx, image.ListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", SnapshotLabel+"="+key),
+			filters.Arg("reference", tag),
+		),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to list snapshot images: %w", err)
+	}
+	return len(images) > 0, nil
+}
+
+// buildSnapshot starts the base image, prepares the database and commits the stopped container as tag
+func buildSnapshot(ctx context.Context, cli *testcontainers.DockerClient, config *PostgreSQLConfig, key, tag string) error {
+	ctr, err := runContainer(ctx, config.imageReference(), containerOptions(config, 2), false)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = ctr.Terminate(ctx)
+	}()
+
+	host, err := ctr.Host(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get snapshot container host: %w", err)
+	}
+	port, err := ctr.MappedPort(ctx, "5432")
+	if err != nil {
+		return fmt.Errorf("failed to get snapshot container port: %w", err)
+	}
+	databaseURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		config.Username, config.Password, resolveHost(host, containerRuntime()), port.Port(), config.DatabaseName)
+
+	pool, err := newPool(ctx, databaseURL, config)
+	if err != nil {
+		return err
+	}
+	err = pool.Ping(ctx)
+	if err == nil {
+		err = prepareDatabase(ctx, pool, databaseURL, config, config.startupMigrator())
+	} else {
+		err = fmt.Errorf("%w: %v", ErrDatabaseConnFailed, err)
+	}
+	pool.Close()
+	if err != nil {
+		return err
+	}
+
+	// A clean shutdown leaves a data directory that starts without crash recovery
+	timeout := config.StartupTimeout
+	if err := ctr.Stop(ctx, &timeout); err != nil {
+		return fmt.Errorf("failed to stop snapshot container: %w", err)
+	}
+
+	_, err = cli.ContainerCommit(ctx, ctr.GetContainerID(), container.CommitOptions{
+		Reference: tag,
+		Comment:   "Prepared database snapshot of " + config.imageReference(),
+		Config: &container.Config{
+			Labels: map[string]string{
+				SnapshotLabel:          key,
+				SnapshotBaseImageLabel: config.imageReference(),
+				// The container's session label would otherwise let Ryuk delete the image
+				testcontainersSessionLabel: "",
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit snapshot image %s: %w", tag, err)
+	}
+
+	return nil
+}
+
+// PruneSnapshotCache removes snapshot images created more than olderThan ago and returns their
+// references. Pass 0 to remove every snapshot. Images still used by a container are kept and
+// reported in the returned error.
+func PruneSnapshotCache(ctx context.Context, olderThan time.Duration) ([]string, error) {
+	// The client below takes its host from testcontainers' cached configuration, which must
+	// point at the detected runtime's socket (Podman, Colima, ...) before it is first read
+	containerRuntime()
+
+	cli, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDockerNotAvailable, err)
+	}
+	defer cli.Close()
+
+	images, err := cli.ImageList(ctx, image.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", SnapshotLabel)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshot images: %w", err)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	var removed []string
+	var errs []error
+	for _, img := range images {
+		if time.Unix(img.Created, 0).After(cutoff) {
+			continue
+		}
+
+		ref := img.ID
+		if len(img.RepoTags) > 0 {
+			ref = img.RepoTags[0]
+		}
+		if _, err := cli.ImageRemove(ctx, ref, image.RemoveOptions{PruneChildren: true}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove snapshot image %s: %w", ref, err))
+			continue
+		}
+		removed = append(removed, ref)
+	}
+
+	slices.Sort(removed)
+	return removed, errors.Join(errs...)
+}
@@ -0,0 +1,65 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSnapshotCache(t *testing.T) {
+	opts := []Option{
+		WithDatabase("snapshotdb"),
+		WithMigrationsFS(embeddedMigrations, "testdata/migrations"),
+		WithSnapshotCache(),
+	}
+
+	// The first start builds the snapshot, the second starts from it
+	for _, run := range []string{"miss", "hit"} {
+		tc := New(t, opts...)
+
+		var exists bool
+		err := tc.Pool.QueryRow(context.Background(), "SELECT to_regclass('widgets') IS NOT NULL").Scan(&exists)
+		if err != nil {
+			t.Fatalf("Failed to check widgets table on cache %s: %v", run, err)
+		}
+		if !exists {
+			t.Errorf("Expected widgets table to exist on cache %s", run)
+		}
+
+		version, dirty, err := tc.MigrationVersion(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to read migration version on cache %s: %v", run, err)
+		}
+		if version != 1 || dirty {
+			t.Errorf("Expected clean migration version 1 on cache %s, got %d (dirty %v)", run, version, dirty)
+		}
+	}
+
+	key, err := snapshotKey(mustBuildConfig(t, opts...))
+	if err != nil {
+		t.Fatalf("Failed to compute snapshot key: %v", err)
+	}
+
+	removed, err := PruneSnapshotCache(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("Failed to prune snapshot cache: %v", err)
+	}
+	found := false
+	for _, ref := range removed {
+		found = found || ref == snapshotTag(key)
+	}
+	if !found {
+		t.Errorf("Expected %s to be pruned, got %v", snapshotTag(key), removed)
+	}
+}
+
+func mustBuildConfig(t *testing.T, opts ...Option) *PostgreSQLConfig {
+	t.Helper()
+
+	config, err := buildConfig(opts...)
+	if err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+	return config
+}
@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// customMigrator is a Migrator the package cannot hash
+type customMigrator struct{}
+
+func (customMigrator) Migrate(ctx context.Context, databaseURL string, pool *pgxpool.Pool) error {
+	return nil
+}
+
+func (customMigrator) MigrationsTable() string {
+	return "custom_migrations"
+}
+
+func snapshotTestConfig(migrations fstest.MapFS) *PostgreSQLConfig {
+	config := DefaultPostgreSQLConfig()
+	config.SnapshotCache = true
+	config.RunMigrations = true
+	if migrations != nil {
+		config.MigrationsFS = migrations
+	}
+	return config
+}
+
+func TestSnapshotKey(t *testing.T) {
+	migrations := fstest.MapFS{
+		"001_init.up.sql": {Data: []byte("CREATE TABLE widgets (id INT)")},
+	}
+
+	base, err := snapshotKey(snapshotTestConfig(migrations))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	again, err := snapshotKey(snapshotTestConfig(migrations))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if again != base {
+		t.Errorf("Expected the key to be stable, got %s and %s", base, again)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *PostgreSQLConfig)
+		same   bool
+	}{
+		{
+			name: "migration content",
+			modify: func(c *PostgreSQLConfig) {
+				c.MigrationsFS = fstest.MapFS{"001_init.up.sql": {Data: []byte("CREATE TABLE gadgets (id INT)")}}
+			},
+		},
+		{
+			name:   "image tag",
+			modify: func(c *PostgreSQLConfig) { c.PostgreSQLVersion = "17-3.5" },
+		},
+		{
+			name:   "database name",
+			modify: func(c *PostgreSQLConfig) { c.DatabaseName = "otherdb" },
+		},
+		{
+			name:   "extensions",
+			modify: func(c *PostgreSQLConfig) { c.Extensions = []Extension{{Name: "pg_trgm"}} },
+		},
+		{
+			name:   "migrations disabled",
+			modify: func(c *PostgreSQLConfig) { c.RunMigrations = false },
+		},
+		{
+			name:   "migrator",
+			modify: func(c *PostgreSQLConfig) { c.Migrator = NewSQLFilesMigrator(migrations, ".") },
+		},
+		{
+			name:   "performance profile",
+			modify: func(c *PostgreSQLConfig) { c.PerformanceProfile = ProfileFast },
+			same:   true,
+		},
+		{
+			name:   "pool size",
+			modify: func(c *PostgreSQLConfig) { c.MaxConns = 50 },
+			same:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := snapshotTestConfig(migrations)
+			tt.modify(config)
+
+			key, err := snapshotKey(config)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if (key == base) != tt.same {
+				t.Errorf("Expected key equality to be %v, got %s and %s", tt.same, base, key)
+			}
+		})
+	}
+}
+
+func TestSnapshotKey_CustomMigrator(t *testing.T) {
+	config := snapshotTestConfig(nil)
+	config.Migrator = customMigrator{}
+
+	_, err := snapshotKey(config)
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestSnapshotKey_MissingMigrations(t *testing.T) {
+	config := snapshotTestConfig(nil)
+	config.MigrationsPath = t.TempDir() + "/missing"
+
+	_, err := snapshotKey(config)
+	if !errors.Is(err, ErrMigrationsFailed) {
+		t.Errorf("Expected ErrMigrationsFailed, got %v", err)
+	}
+}
+
+func TestSnapshotTag(t *testing.T) {
+	tag := snapshotTag(strings.Repeat("ab", 32))
+	if tag != snapshotRepository+":abababababababab" {
+		t.Errorf("Expected tag to use the first 16 characters of the key, got %s", tag)
+	}
+	if !imageReferencePattern.MatchString(tag) {
+		t.Errorf("Expected %s to be a valid image reference", tag)
+	}
+}
+
+func TestValidate_SnapshotCache(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *PostgreSQLConfig)
+		wantErr bool
+	}{
+		{
+			name:   "default migrator",
+			modify: func(c *PostgreSQLConfig) {},
+		},
+		{
+			name:   "built-in migrator",
+			modify: func(c *PostgreSQLConfig) { c.Migrator = NewGooseMigrator(fstest.MapFS{}, ".") },
+		},
+		{
+			name:    "custom migrator",
+			modify:  func(c *PostgreSQLConfig) { c.Migrator = customMigrator{} },
+			wantErr: true,
+		},
+		{
+			name: "custom migrator without migrations",
+			modify: func(c *PostgreSQLConfig) {
+				c.Migrator = customMigrator{}
+				c.RunMigrations = false
+			},
+		},
+		{
+			name:    "reuse",
+			modify:  func(c *PostgreSQLConfig) { c.ReuseContainer = true },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := snapshotTestConfig(fstest.MapFS{})
+			tt.modify(config)
+
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+}
+
+func TestContainerOptions_SnapshotCache(t *testing.T) {
+	config := DefaultPostgreSQLConfig()
+	config.PerformanceProfile = ProfileFast
+	config.SnapshotCache = true
+
+	req := testcontainers.GenericContainerRequest{}
+	for _, opt := range containerOptions(config, 1) {
+		if err := opt.Customize(&req); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if len(req.Tmpfs) != 0 {
+		t.Errorf("Expected no tmpfs for a snapshot container, got %v", req.Tmpfs)
+	}
+	if req.Env["PGDATA"] != snapshotPGDATA {
+		t.Errorf("Expected PGDATA to be %s, got %s", snapshotPGDATA, req.Env["PGDATA"])
+	}
+}
@@ -0,0 +1,224 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+// startupLogLines is how many lines of container logs a StartupError keeps
+const startupLogLines = 50
+
+// StartupPhase names the step of starting a container that failed
+type StartupPhase string
+
+const (
+	PhasePull    StartupPhase = "pull"    // Finding the Docker host and pulling the image
+	PhaseCreate  StartupPhase = "create"  // Creating the container, or the test database on an external server or reused container
+	PhaseStart   StartupPhase = "start"   // Starting the container and publishing its port
+	PhaseWait    StartupPhase = "wait"    // Waiting for the server to accept connections
+	PhaseMigrate StartupPhase = "migrate" // Installing extensions, running scripts and migrations
+	PhasePool    StartupPhase = "pool"    // Creating the connection pool
+	PhasePing    StartupPhase = "ping"    // Connecting to the database
+)
+
+// StartupError reports which phase of starting a container failed, with the container's last
+// log lines. It unwraps to the matching sentinel error, e.g. ErrContainerStartTimeout.
+type StartupError struct {
+	Phase       StartupPhase
+	Image       string   // Image the container was started from; empty for an external server
+	ContainerID string   // Empty when the container was never created
+	Logs        []string // Last lines of the container's logs
+	Err         error
+}
+
+func (e *StartupError) Error() string {
+	var details []string
+	if e.Image != "" {
+		details = append(details, "image "+e.Image)
+	}
+	if e.ContainerID != "" {
+		details = append(details, "container "+shortID(e.ContainerID))
+	}
+
+	msg := fmt.Sprintf("PostgreSQL startup failed in %s phase", e.Phase)
+	if len(details) > 0 {
+		msg += " (" + strings.Join(details, ", ") + ")"
+	}
+	if sentinel := e.sentinel(); sentinel != nil {
+		msg += ": " + sentinel.Error()
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Unwrap returns the matching sentinel error, if any, and the underlying error
+func (e *StartupError) Unwrap() []error {
+	if sentinel := e.sentinel(); sentinel != nil {
+		return []error{sentinel, e.Err}
+	}
+	return []error{e.Err}
+}
+
+// sentinel classifies the failure by its phase and error chain
+func (e *StartupError) sentinel() error {
+	switch e.Phase {
+	case PhasePull, PhaseCreate, PhaseStart, PhaseWait:
+		if errors.Is(e.Err, context.DeadlineExceeded) {
+			return ErrContainerStartTimeout
+		}
+		if e.Phase == PhaseStart && isPortConflict(e.Err) {
+			return ErrContainerPortConflict
+		}
+	case PhasePool, PhasePing:
+		if !errors.Is(e.Err, ErrDatabaseConnFailed) {
+			return ErrDatabaseConnFailed
+		}
+	}
+	return nil
+}
+
+// isPortConflict reports whether the engine refused to publish the container's port.
+// The Docker API reports this as a plain server error, so only its message identifies it.
+func isPortConflict(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "port is already allocated") || strings.Contains(msg, "address already in use")
+}
+
+// shortID abbreviates a container ID like the docker CLI does
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// startupTracker records how far testcontainers got through the container lifecycle
+type startupTracker struct {
+	mu          sync.Mutex
+	phase       StartupPhase
+	containerID string
+}
+
+// set records the phase about to run
+func (t *startupTracker) set(phase StartupPhase, containerID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phase = phase
+	if containerID != "" {
+		t.containerID = containerID
+	}
+}
+
+// state returns the phase that was running and the container, if created
+func (t *startupTracker) state() (StartupPhase, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.phase, t.containerID
+}
+
+// hooks advance the phase. testcontainers pulls the image before the pre-create hooks and runs
+// the wait strategy after user-defined post-start hooks.
+func (t *startupTracker) hooks() testcontainers.ContainerCustomizer {
+	return testcontainers.WithAdditionalLifecycleHooks(testcontainers.ContainerLifecycleHooks{
+		PreCreates: []testcontainers.ContainerRequestHook{
+			func(ctx context.Context, req testcontainers.ContainerRequest) error {
+				t.set(PhaseCreate, "")
+				return nil
+			},
+		},
+		PostCreates: []testcontainers.ContainerHook{
+			func(ctx context.Context, c testcontainers.Container) error {
+				t.set(PhaseStart, c.GetContainerID())
+				return nil
+			},
+		},
+		PostStarts: []testcontainers.ContainerHook{
+			func(ctx context.Context, c testcontainers.Container) error {
+				t.set(PhaseWait, c.GetContainerID())
+				return nil
+			},
+		},
+	})
+}
+
+// runContainer starts a PostgreSQL container from image and reports failures as a *StartupError.
+// A container that failed to start is terminated unless keep is set.
+func runContainer(ctx context.Context, image string, opts []testcontainers.ContainerCustomizer, keep bool) (ctr *postgres.PostgresContainer, err error) {
+	tracker := &startupTracker{phase: PhasePull}
+
+	// testcontainers panics when no Docker host can be found
+	defer func() {
+		if r := recover(); r != nil {
+			ctr = nil
+			err = &StartupError{Phase: PhasePull, Image: image, Err: fmt.Errorf("%w: %v", ErrDockerNotAvailable, r)}
+		}
+	}()
+
+	ctr, err = postgres.Run(ctx, image, append(slices.Clone(opts), tracker.hooks())...)
+	if err == nil {
+		return ctr, nil
+	}
+
+	phase, containerID := tracker.state()
+	startErr := newStartupError(ctx, phase, image, containerID, err)
+	if ctr != nil && !keep {
+		_ = ctr.Terminate(context.WithoutCancel(ctx))
+	}
+	return nil, startErr
+}
+
+// newStartupError builds a *StartupError, collecting the container's last log lines
+func newStartupError(ctx context.Context, phase StartupPhase, image, containerID string, err error) *StartupError {
+	startErr := &StartupError{Phase: phase, Image: image, ContainerID: containerID, Err: err}
+	if containerID != "" {
+		startErr.Logs = containerLogs(context.WithoutCancel(ctx), containerID, startupLogLines)
+	}
+	return startErr
+}
+
+// containerLogs returns the last lines of a container's output, or nil when they cannot be read
+func containerLogs(ctx context.Context, containerID string, lines int) []string {
+	cli, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return nil
+	}
+	defer cli.Close()
+
+	logs, err := cli.ContainerLogs(ctx, containerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       strconv.Itoa(lines),
+	})
+	if err != nil {
+		return nil
+	}
+	defer logs.Close()
+
+	var buf bytes.Buffer
+	if _, err := stdcopy.StdCopy(&buf, &buf, logs); err != nil {
+		return nil
+	}
+	return lastLines(buf.String(), lines)
+}
+
+// lastLines returns the last n lines of output
+func lastLines(output string, n int) []string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
@@ -0,0 +1,47 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStartupError_WaitTimeout(t *testing.T) {
+	config, err := buildConfig(WithImageFamily(ImageFamilyPostgres, ""), WithStartupTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to build config: %v", err)
+	}
+
+	// Pull the image first so the timeout is spent waiting for readiness
+	warm, err := buildConfig(WithImageFamily(ImageFamilyPostgres, ""))
+	if err != nil {
+		t.Fatalf("Failed to build config: %v", err)
+	}
+	tc, err := StartPostgreSQLContainer(context.Background(), warm)
+	if err != nil {
+		t.Fatalf("Failed to start container: %v", err)
+	}
+	tc.Close()
+
+	_, err = StartPostgreSQLContainer(context.Background(), config)
+	if !errors.Is(err, ErrContainerStartTimeout) {
+		t.Fatalf("Expected ErrContainerStartTimeout, got %v", err)
+	}
+
+	var startErr *StartupError
+	if !errors.As(err, &startErr) {
+		t.Fatalf("Expected a *StartupError, got %v", err)
+	}
+	if startErr.Phase != PhaseWait {
+		t.Errorf("Expected Phase to be %s, got %s", PhaseWait, startErr.Phase)
+	}
+	if startErr.ContainerID == "" {
+		t.Error("Expected the container ID to be recorded")
+	}
+	if startErr.Image != config.imageReference() {
+		t.Errorf("Expected Image to be %s, got %s", config.imageReference(), startErr.Image)
+	}
+}
@@ -0,0 +1,193 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestStartupError_Sentinels(t *testing.T) {
+	tests := []struct {
+		name  string
+		phase StartupPhase
+		err   error
+		want  error
+	}{
+		{
+			name:  "wait deadline is a start timeout",
+			phase: PhaseWait,
+			err:   fmt.Errorf("wait until ready: %w", context.DeadlineExceeded),
+			want:  ErrContainerStartTimeout,
+		},
+		{
+			name:  "pull deadline is a start timeout",
+			phase: PhasePull,
+			err:   fmt.Errorf("pull image: %w", context.DeadlineExceeded),
+			want:  ErrContainerStartTimeout,
+		},
+		{
+			name:  "port allocated at start is a port conflict",
+			phase: PhaseStart,
+			err:   errors.New("start container: Bind for 0.0.0.0:5432 failed: port is already allocated"),
+			want:  ErrContainerPortConflict,
+		},
+		{
+			name:  "address in use at start is a port conflict",
+			phase: PhaseStart,
+			err:   errors.New("listen tcp 0.0.0.0:5432: bind: address already in use"),
+			want:  ErrContainerPortConflict,
+		},
+		{
+			name:  "pool failure is a connection failure",
+			phase: PhasePool,
+			err:   errors.New("failed to parse database URL"),
+			want:  ErrDatabaseConnFailed,
+		},
+		{
+			name:  "ping failure is a connection failure",
+			phase: PhasePing,
+			err:   errors.New("connection refused"),
+			want:  ErrDatabaseConnFailed,
+		},
+		{
+			name:  "migration failure keeps its own sentinel",
+			phase: PhaseMigrate,
+			err:   fmt.Errorf("%w: dirty database version 3", ErrMigrationsFailed),
+			want:  ErrMigrationsFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := error(&StartupError{Phase: tt.phase, Err: tt.err})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v to wrap %v", err, tt.want)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v to wrap the underlying error", err)
+			}
+		})
+	}
+}
+
+func TestStartupError_NoSubstringMatching(t *testing.T) {
+	tests := []struct {
+		name  string
+		phase StartupPhase
+		err   error
+	}{
+		{
+			name:  "timeout in a migration message",
+			phase: PhaseMigrate,
+			err:   errors.New(`ERROR: column "timeout" does not exist`),
+		},
+		{
+			name:  "port in a create message",
+			phase: PhaseCreate,
+			err:   errors.New("invalid port specification, address already in use"),
+		},
+		{
+			name:  "timeout text without a deadline",
+			phase: PhaseWait,
+			err:   errors.New("container exited: statement timeout setting rejected"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := &StartupError{Phase: tt.phase, Err: tt.err}
+			if errors.Is(err, ErrContainerStartTimeout) {
+				t.Errorf("Expected %v not to be a start timeout", err)
+			}
+			if errors.Is(err, ErrContainerPortConflict) {
+				t.Errorf("Expected %v not to be a port conflict", err)
+			}
+		})
+	}
+}
+
+func TestStartupError_As(t *testing.T) {
+	err := fmt.Errorf("setup: %w", &StartupError{
+		Phase:       PhaseWait,
+		Image:       "postgres:16-alpine",
+		ContainerID: "0123456789abcdef",
+		Logs:        []string{"FATAL: data directory has wrong ownership"},
+		Err:         context.DeadlineExceeded,
+	})
+
+	var startErr *StartupError
+	if !errors.As(err, &startErr) {
+		t.Fatalf("Expected errors.As to find a *StartupError in %v", err)
+	}
+	if startErr.Phase != PhaseWait {
+		t.Errorf("Expected Phase to be %s, got %s", PhaseWait, startErr.Phase)
+	}
+	if len(startErr.Logs) != 1 {
+		t.Errorf("Expected 1 log line, got %d", len(startErr.Logs))
+	}
+}
+
+func TestStartupError_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *StartupError
+		want string
+	}{
+		{
+			name: "container details",
+			err: &StartupError{
+				Phase:       PhaseWait,
+				Image:       "postgres:16-alpine",
+				ContainerID: "0123456789abcdef",
+				Err:         context.DeadlineExceeded,
+			},
+			want: "PostgreSQL startup failed in wait phase (image postgres:16-alpine, container 0123456789ab): container failed to start within timeout period: context deadline exceeded",
+		},
+		{
+			name: "no container",
+			err:  &StartupError{Phase: PhaseMigrate, Err: errors.New("syntax error")},
+			want: "PostgreSQL startup failed in migrate phase: syntax error",
+		},
+		{
+			name: "sentinel already wrapped",
+			err: &StartupError{
+				Phase: PhasePing,
+				Err:   fmt.Errorf("%w: connection refused", ErrDatabaseConnFailed),
+			},
+			want: "PostgreSQL startup failed in ping phase: failed to connect to container database: connection refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		n      int
+		want   []string
+	}{
+		{name: "empty", output: "", n: 3, want: nil},
+		{name: "fewer lines", output: "a\nb\n", n: 3, want: []string{"a", "b"}},
+		{name: "trimmed to n", output: "a\nb\nc\nd\n", n: 2, want: []string{"c", "d"}},
+		{name: "no trailing newline", output: "a\nb", n: 5, want: []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lastLines(tt.output, tt.n)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("lastLines(%q, %d) = %q, want %q", tt.output, tt.n, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxIdentifierLength is the PostgreSQL limit (NAMEDATALEN - 1) for database names
+const maxIdentifierLength = 63
+
+// StartPostgreSQLContainerWithTemplate creates a PostgreSQL container and migrates a template database
+// Use CloneDatabase to give each test its own copy of the migrated schema
+func StartPostgreSQLContainerWithTemplate(ctx context.Context, migrationsPath string) (*PostgreSQLTestContainer, error) {
+	tc, err := StartPostgreSQLContainerWithCheck(ctx, DefaultPostgreSQLConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tc.PrepareTemplate(ctx, migrationsPath); err != nil {
+		_ = tc.Close() // Cleanup on error
+		return nil, err
+	}
+
+	return tc, nil
+}
+
+// PrepareTemplate creates a template database and runs migrations into it once.
+// Subsequent calls are no-ops, so it is safe to call from every test.
+//
+// A non-empty migrationsPath runs golang-migrate migrations from that path; an empty one
+// uses the container's configured Migrator (by default golang-migrate on MigrationsFS or
+// MigrationsPath, falling back to FindMigrationsPath).
+//
+// The template is marked with is_template and has connections disabled, which
+// keeps it free of the sessions that would otherwise block CREATE DATABASE ... TEMPLATE.
+func (tc *PostgreSQLTestContainer) PrepareTemplate(ctx context.Context, migrationsPath string) error {
+	tc.templateMu.Lock()
+	defer tc.templateMu.Unlock()
+
+	if tc.templateName != "" {
+		return nil
+	}
+
+	templateName := truncateIdentifier(tc.DatabaseName + "_template")
+	quoted := pgx.Identifier{templateName}.Sanitize()
+
+	if _, err := tc.Pool.Exec(ctx, "CREATE DATABASE "+quoted); err != nil {
+		return fmt.Errorf("failed to create template database %s: %w", templateName, err)
+	}
+
+	templateURL, err := tc.databaseURLFor(templateName)
+	if err != nil {
+		tc.dropDatabase(ctx, templateName)
+		return err
+	}
+
+	if err := tc.prepareTemplate(ctx, templateURL, migrationsPath); err != nil {
+		tc.dropDatabase(ctx, templateName)
+		return err
+	}
+
+	if _, err := tc.Pool.Exec(ctx, "ALTER DATABASE "+quoted+" WITH is_template = true ALLOW_CONNECTIONS = false"); err != nil {
+		tc.dropDatabase(ctx, templateName)
+		return fmt.Errorf("failed to mark %s as template: %w", templateName, err)
+	}
+
+	tc.templateName = templateName
+	return nil
+}
+
+// prepareTemplate installs the configured extensions and runs the configured scripts
+// and migrations in the template database
+func (tc *PostgreSQLTestContainer) prepareTemplate(ctx context.Context, templateURL, migrationsPath string) error {
+	config := tc.config
+	if config == nil {
+		config = &PostgreSQLConfig{}
+	}
+
+	pool, err := pgxpool.New(ctx, templateURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseConnFailed, err)
+	}
+	// Closed before the template is used, since CREATE DATABASE ... TEMPLATE needs it idle
+	defer pool.Close()
+
+	migrator := config.migrator()
+	if migrationsPath != "" {
+		migrator = &golangMigrator{source: migrationSource{path: migrationsPath}, logf: config.logf}
+	}
+
+	return prepareDatabase(ctx, pool, templateURL, config, migrator)
+}
+
+// CloneDatabase creates a fresh copy of the template database for the calling test.
+// The clone has its own small connection pool (see CloneMaxConns) and is dropped when the test
+// finishes, so parallel tests never see each other's data.
+//
+// PrepareTemplate must have been called first.
+func (tc *PostgreSQLTestContainer) CloneDatabase(t testing.TB) *TestDatabase {
+	t.Helper()
+
+	tc.templateMu.Lock()
+	templateName := tc.templateName
+	tc.templateMu.Unlock()
+
+	if templateName == "" {
+		t.Fatal("CloneDatabase called before PrepareTemplate")
+	}
+
+	ctx := context.Background()
+	name := cloneDatabaseName(tc.DatabaseName, t.Name(), tc.cloneSeq.Add(1))
+
+	createSQL := fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s",
+		pgx.Identifier{name}.Sanitize(), pgx.Identifier{templateName}.Sanitize())
+	if _, err := tc.Pool.Exec(ctx, createSQL); err != nil {
+		t.Fatalf("failed to clone template database %s: %v", templateName, err)
+	}
+
+	databaseURL, err := tc.databaseURLFor(name)
+	if err != nil {
+		tc.dropDatabase(ctx, name)
+		t.Fatalf("failed to build URL for %s: %v", name, err)
+	}
+
+	pool, err := newPool(ctx, databaseURL, tc.config.clonePoolConfig())
+	if err != nil {
+		tc.dropDatabase(ctx, name)
+		t.Fatalf("failed to connect to cloned database %s: %v", name, err)
+	}
+
+	db := tc.trackDatabase(name, databaseURL, pool)
+	t.Cleanup(func() {
+		if err := db.Drop(context.Background()); err != nil {
+			t.Logf("Warning: failed to drop cloned database %s: %v", name, err)
+		}
+	})
+
+	return db
+}
+
+// defaultCloneMaxConns is the pool size of a cloned database when CloneMaxConns is not set
+const defaultCloneMaxConns = 2
+
+// clonePoolConfig returns the pool settings of a cloned database: at most CloneMaxConns
+// connections and none kept open while idle
+func (c *PostgreSQLConfig) clonePoolConfig() *PostgreSQLConfig {
+	if c == nil {
+		c = DefaultPostgreSQLConfig()
+	}
+	pool := *c
+	pool.MaxConns = c.CloneMaxConns
+	if pool.MaxConns <= 0 {
+		pool.MaxConns = defaultCloneMaxConns
+	}
+	pool.MinConns = 0
+	return &pool
+}
+
+// dropTemplate drops the template database created by PrepareTemplate, if any. A template
+// database cannot be dropped, so it is unmarked first.
+func (tc *PostgreSQLTestContainer) dropTemplate(ctx context.Context) error {
+	tc.templateMu.Lock()
+	defer tc.templateMu.Unlock()
+
+	if tc.templateName == "" {
+		return nil
+	}
+
+	quoted := pgx.Identifier{tc.templateName}.Sanitize()
+	if _, err := tc.Pool.Exec(ctx, "ALTER DATABASE "+quoted+" WITH is_template = false"); err != nil {
+		return fmt.Errorf("failed to unmark template database %s: %w", tc.templateName, err)
+	}
+	if err := tc.dropDatabaseErr(ctx, tc.templateName); err != nil {
+		return err
+	}
+
+	tc.templateName = ""
+	return nil
+}
+
+// dropDatabase drops a database, ignoring errors (used on error paths)
+func (tc *PostgreSQLTestContainer) dropDatabase(ctx context.Context, name string) {
+	_ = tc.dropDatabaseErr(ctx, name)
+}
+
+// dropDatabaseErr drops a database, disconnecting any remaining sessions
+func (tc *PostgreSQLTestContainer) dropDatabaseErr(ctx context.Context, name string) error {
+	dropSQL := fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)", pgx.Identifier{name}.Sanitize())
+	if _, err := tc.Pool.Exec(ctx, dropSQL); err != nil {
+		return fmt.Errorf("failed to drop database %s: %w", name, err)
+	}
+	return nil
+}
+
+var nonIdentifierChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// cloneDatabaseName builds a unique, readable database name from the test name
+func cloneDatabaseName(base, testName string, seq uint64) string {
+	suffix := fmt.Sprintf("_%d", seq)
+	slug := strings.Trim(nonIdentifierChars.ReplaceAllString(strings.ToLower(testName), "_"), "_")
+
+	name := base + "_" + slug
+	if slug == "" {
+		name = base
+	}
+	if len(name)+len(suffix) > maxIdentifierLength {
+		name = name[:maxIdentifierLength-len(suffix)]
+	}
+	return name + suffix
+}
+
+// truncateIdentifier shortens name to the PostgreSQL identifier limit
+func truncateIdentifier(name string) string {
+	if len(name) > maxIdentifierLength {
+		return name[:maxIdentifierLength]
+	}
+	return name
+}
@@ -0,0 +1,117 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeUsersMigration(t *testing.T) string {
+	t.Helper()
+
+	migrationsDir := filepath.Join(t.TempDir(), "migrations")
+	if err := os.MkdirAll(migrationsDir, 0o755); err != nil {
+		t.Fatalf("Failed to create migrations directory: %v", err)
+	}
+
+	up := "CREATE TABLE users (id SERIAL PRIMARY KEY, name TEXT NOT NULL);"
+	if err := os.WriteFile(filepath.Join(migrationsDir, "001_create_users.up.sql"), []byte(up), 0o644); err != nil {
+		t.Fatalf("Failed to write migration file: %v", err)
+	}
+
+	down := "DROP TABLE IF EXISTS users;"
+	if err := os.WriteFile(filepath.Join(migrationsDir, "001_create_users.down.sql"), []byte(down), 0o644); err != nil {
+		t.Fatalf("Failed to write migration file: %v", err)
+	}
+
+	return migrationsDir
+}
+
+func TestCloneDatabase(t *testing.T) {
+	ctx := context.Background()
+
+	tc, err := StartPostgreSQLContainerWithTemplate(ctx, writeUsersMigration(t))
+	if err != nil {
+		t.Fatalf("Failed to start PostgreSQL container with template: %v", err)
+	}
+	// Parallel subtests run after this function returns, so a deferred Close would be too early
+	t.Cleanup(func() { _ = tc.Close() })
+
+	for i := 0; i < 5; i++ {
+		t.Run(fmt.Sprintf("clone-%d", i), func(t *testing.T) {
+			t.Parallel()
+
+			db := tc.CloneDatabase(t)
+
+			// Each clone starts empty and only sees its own writes
+			if _, err := db.Pool.Exec(ctx, "INSERT INTO users (name) VALUES ('Alice')"); err != nil {
+				t.Fatalf("Failed to insert into cloned database: %v", err)
+			}
+
+			var count int
+			if err := db.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+				t.Fatalf("Failed to count users: %v", err)
+			}
+			if count != 1 {
+				t.Errorf("Expected 1 user in clone, got %d", count)
+			}
+		})
+	}
+}
+
+func TestCloneDatabase_DroppedAfterTest(t *testing.T) {
+	ctx := context.Background()
+
+	tc, err := StartPostgreSQLContainerWithTemplate(ctx, writeUsersMigration(t))
+	if err != nil {
+		t.Fatalf("Failed to start PostgreSQL container with template: %v", err)
+	}
+	defer tc.Close()
+
+	var cloneName string
+	t.Run("clone", func(t *testing.T) {
+		cloneName = tc.CloneDatabase(t).Name
+	})
+
+	var exists bool
+	err = tc.Pool.QueryRow(ctx, "SELECT EXISTS (SELECT FROM pg_database WHERE datname = $1)", cloneName).Scan(&exists)
+	if err != nil {
+		t.Fatalf("Failed to check for cloned database: %v", err)
+	}
+	if exists {
+		t.Errorf("Expected cloned database %s to be dropped after the test", cloneName)
+	}
+}
+
+func TestPrepareTemplate_DroppedOnClose(t *testing.T) {
+	ctx := context.Background()
+
+	tc, err := StartPostgreSQLContainerWithTemplate(ctx, writeUsersMigration(t))
+	if err != nil {
+		t.Fatalf("Failed to start PostgreSQL container with template: %v", err)
+	}
+	defer tc.Close()
+
+	templateName := tc.templateName
+	if err := tc.dropTemplate(ctx); err != nil {
+		t.Fatalf("Failed to drop template: %v", err)
+	}
+
+	var exists bool
+	err = tc.Pool.QueryRow(ctx, "SELECT EXISTS (SELECT FROM pg_database WHERE datname = $1)", templateName).Scan(&exists)
+	if err != nil {
+		t.Fatalf("Failed to check for template database: %v", err)
+	}
+	if exists {
+		t.Errorf("Expected template database %s to be dropped", templateName)
+	}
+
+	// The template can be prepared again once dropped
+	if err := tc.PrepareTemplate(ctx, writeUsersMigration(t)); err != nil {
+		t.Errorf("Failed to prepare template again: %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// New starts a PostgreSQL container for the calling test.
+//
+// It skips the test when Docker is unavailable, fails it when the container
+// cannot be started, and closes the container via t.Cleanup. Warnings are
+// reported through t.Logf unless the configuration provides its own Logf.
+func New(t testing.TB, opts ...Option) *PostgreSQLTestContainer {
+	t.Helper()
+
+	config, err := buildConfig(opts...)
+	if err != nil {
+		t.Fatalf("Invalid PostgreSQL configuration: %v", err)
+	}
+	if config.Logf == nil {
+		config.Logf = t.Logf
+	}
+
+	tc, err := StartPostgreSQLContainerWithCheck(context.Background(), config)
+	if errors.Is(err, ErrDockerNotAvailable) {
+		t.Skipf("Skipping test: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("Failed to start PostgreSQL container: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := tc.Close(); err != nil {
+			t.Logf("Warning: failed to cleanup PostgreSQL container: %v", err)
+		}
+	})
+
+	return tc
+}
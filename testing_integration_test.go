@@ -0,0 +1,28 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tc := New(t, WithConfig(&PostgreSQLConfig{
+		DatabaseName:      "newdb",
+		Username:          "newuser",
+		Password:          "newpass",
+		PostgreSQLVersion: "16-3.4",
+		MaxConns:          5,
+		MinConns:          1,
+		StartupTimeout:    DefaultPostgreSQLConfig().StartupTimeout,
+	}))
+
+	if tc.DatabaseName != "newdb" {
+		t.Errorf("Expected database name to be newdb, got %s", tc.DatabaseName)
+	}
+
+	if err := tc.Pool.Ping(context.Background()); err != nil {
+		t.Fatalf("Failed to ping database: %v", err)
+	}
+}
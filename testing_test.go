@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPostgreSQLConfig_Logf(t *testing.T) {
+	var messages []string
+	config := DefaultPostgreSQLConfig()
+	config.Logf = func(format string, args ...any) {
+		messages = append(messages, fmt.Sprintf(format, args...))
+	}
+
+	tc := &PostgreSQLTestContainer{config: config}
+	tc.logf("Warning: %s", "something happened")
+
+	if len(messages) != 1 || messages[0] != "Warning: something happened" {
+		t.Errorf("Expected warning to be routed to Logf, got %v", messages)
+	}
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DBTX is the query interface shared by *pgxpool.Pool, *pgx.Conn, pgx.Tx and TestTx.
+// Accepting DBTX in repository code lets tests pass a TestTx instead of a pool.
+type DBTX interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+var (
+	_ DBTX = (*pgxpool.Pool)(nil)
+	_ DBTX = (*pgx.Conn)(nil)
+	_ DBTX = pgx.Tx(nil)
+	_ DBTX = (*TestTx)(nil)
+)
+
+// TestTx is a transaction that is rolled back when the test finishes.
+// It deliberately has no Commit or Rollback, so code under test cannot end it.
+// Like any transaction it holds a single connection and must not be used concurrently.
+type TestTx struct {
+	tx pgx.Tx
+}
+
+// BeginTestTx starts a transaction for the calling test and rolls it back via t.Cleanup.
+// Nothing written through the returned handle is visible to other tests, so tests using
+// it can run with t.Parallel() without cleaning tables. Each handle holds one pool
+// connection for the lifetime of the test.
+func (tc *PostgreSQLTestContainer) BeginTestTx(t testing.TB) *TestTx {
+	t.Helper()
+
+	tx, err := tc.Pool.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to begin test transaction: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := tx.Rollback(context.Background()); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			t.Logf("Warning: failed to roll back test transaction: %v", err)
+		}
+	})
+
+	return &TestTx{tx: tx}
+}
+
+// Exec executes sql inside the test transaction
+func (t *TestTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return t.tx.Exec(ctx, sql, arguments...)
+}
+
+// Query runs a query inside the test transaction
+func (t *TestTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return t.tx.Query(ctx, sql, args...)
+}
+
+// QueryRow runs a single-row query inside the test transaction
+func (t *TestTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return t.tx.QueryRow(ctx, sql, args...)
+}
+
+// Begin starts a nested transaction backed by a SAVEPOINT.
+// Committing it releases the savepoint; rolling it back undoes only its own changes.
+func (t *TestTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	return t.tx.Begin(ctx)
+}
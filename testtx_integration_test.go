@@ -0,0 +1,54 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBeginTestTx(t *testing.T) {
+	ctx := context.Background()
+	tc := New(t)
+
+	if _, err := tc.Pool.Exec(ctx, "CREATE TABLE test_tx (id SERIAL PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	t.Run("writes", func(t *testing.T) {
+		tx := tc.BeginTestTx(t)
+
+		if _, err := tx.Exec(ctx, "INSERT INTO test_tx (name) VALUES ('outer')"); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+
+		// A nested transaction rolled back by the code under test only undoes its own work
+		nested, err := tx.Begin(ctx)
+		if err != nil {
+			t.Fatalf("Failed to begin nested transaction: %v", err)
+		}
+		if _, err := nested.Exec(ctx, "INSERT INTO test_tx (name) VALUES ('nested')"); err != nil {
+			t.Fatalf("Failed to insert in nested transaction: %v", err)
+		}
+		if err := nested.Rollback(ctx); err != nil {
+			t.Fatalf("Failed to roll back nested transaction: %v", err)
+		}
+
+		var count int
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM test_tx").Scan(&count); err != nil {
+			t.Fatalf("Failed to count rows: %v", err)
+		}
+		if count != 1 {
+			t.Errorf("Expected 1 row inside the test transaction, got %d", count)
+		}
+	})
+
+	// The test transaction was rolled back when the subtest finished
+	var count int
+	if err := tc.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM test_tx").Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected 0 rows after rollback, got %d", count)
+	}
+}
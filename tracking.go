@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// trackingSchema holds the objects used to track which tables were written to
+	trackingSchema = "_pgtc"
+
+	// trackingTrigger is the statement-level trigger that marks a table dirty
+	trackingTrigger = "_pgtc_track_dirty"
+)
+
+// trackingSQL installs dirty-table tracking. Every user table gets a statement-level trigger that
+// records its oid in _pgtc.dirty_tables, and an event trigger does the same for tables created
+// later, e.g. by tests or by MigrateUp. The marks table has no unique constraint, so concurrent
+// writers never wait on each other. Running it again re-marks every table dirty.
+const trackingSQL = `
+CREATE SCHEMA IF NOT EXISTS _pgtc;
+
+CREATE UNLOGGED TABLE IF NOT EXISTS _pgtc.dirty_tables (relid oid NOT NULL);
+CREATE INDEX IF NOT EXISTS dirty_tables_relid ON _pgtc.dirty_tables (relid);
+
+CREATE OR REPLACE FUNCTION _pgtc.mark_dirty() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM _pgtc.dirty_tables WHERE relid = TG_RELID) THEN
+		INSERT INTO _pgtc.dirty_tables (relid) VALUES (TG_RELID);
+	END IF;
+	RETURN NULL;
+END
+$$;
+
+CREATE OR REPLACE FUNCTION _pgtc.track_table(rel regclass) RETURNS void LANGUAGE plpgsql AS $$
+BEGIN
+	EXECUTE format('DROP TRIGGER IF EXISTS _pgtc_track_dirty ON %s', rel);
+	EXECUTE format('CREATE TRIGGER _pgtc_track_dirty AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON %s FOR EACH STATEMENT EXECUTE FUNCTION _pgtc.mark_dirty()', rel);
+	INSERT INTO _pgtc.dirty_tables (relid)
+	SELECT rel WHERE NOT EXISTS (SELECT 1 FROM _pgtc.dirty_tables WHERE relid = rel);
+END
+$$;
+
+CREATE OR REPLACE FUNCTION _pgtc.track_new_tables() RETURNS event_trigger LANGUAGE plpgsql AS $$
+DECLARE
+	rel oid;
+BEGIN
+	FOR rel IN
+		SELECT c.oid
+		FROM pg_event_trigger_ddl_commands() cmd
+		JOIN pg_class c ON c.oid = cmd.objid
+		WHERE cmd.classid = 'pg_class'::regclass
+		AND c.relkind IN ('r', 'p')
+		AND c.relpersistence <> 't'
+		AND c.relnamespace <> '_pgtc'::regnamespace
+	LOOP
+		PERFORM _pgtc.track_table(rel);
+	END LOOP;
+END
+$$;
+
+DROP EVENT TRIGGER IF EXISTS _pgtc_track_new_tables;
+CREATE EVENT TRIGGER _pgtc_track_new_tables ON ddl_command_end
+	WHEN TAG IN ('CREATE TABLE', 'CREATE TABLE AS', 'SELECT INTO')
+	EXECUTE FUNCTION _pgtc.track_new_tables();
+
+SELECT _pgtc.track_table(c.oid)
+FROM pg_class c
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('r', 'p')
+AND c.relpersistence <> 't'
+AND n.nspname NOT IN ('pg_catalog', 'information_schema', '_pgtc')
+AND n.nspname NOT LIKE 'pg\_toast%'
+AND NOT EXISTS (
+	SELECT 1 FROM pg_depend dep
+	WHERE dep.classid = 'pg_class'::regclass AND dep.objid = c.oid AND dep.deptype = 'e'
+);
+`
+
+// installDirtyTracking installs the triggers that record which tables receive writes.
+// It needs superuser rights for the event trigger.
+func installDirtyTracking(ctx context.Context, db DBTX) error {
+	if _, err := db.Exec(ctx, trackingSQL); err != nil {
+		return fmt.Errorf("failed to install dirty table tracking: %w", err)
+	}
+	return nil
+}
+
+// clearDirtyMarks forgets that tables were written to, or that any table was when all is set
+func clearDirtyMarks(ctx context.Context, tx pgx.Tx, tables []string, all bool) error {
+	var err error
+	if all {
+		_, err = tx.Exec(ctx, "DELETE FROM "+trackingSchema+".dirty_tables")
+	} else {
+		_, err = tx.Exec(ctx, "DELETE FROM "+trackingSchema+".dirty_tables WHERE relid IN (SELECT t::regclass FROM unnest($1::text[]) AS t)", tables)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to clear dirty table marks: %w", err)
+	}
+	return nil
+}
+
+// DirtyTables lists the tables written to since the last clean, as schema-qualified names.
+// It requires CleanOptions.TrackDirtyTables.
+func (tc *PostgreSQLTestContainer) DirtyTables(ctx context.Context) ([]string, error) {
+	rows, err := tc.Pool.Query(ctx, `
+		SELECT DISTINCT format('%I.%I', n.nspname, c.relname)
+		FROM `+trackingSchema+`.dirty_tables d
+		JOIN pg_class c ON c.oid = d.relid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		ORDER BY 1
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dirty tables: %w", err)
+	}
+
+	tables, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan dirty tables: %w", err)
+	}
+	return tables, nil
+}
@@ -0,0 +1,83 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDirtyTableTracking(t *testing.T) {
+	ctx := context.Background()
+	tc := New(t, WithImageFamily(ImageFamilyPostgres, ""), WithCleaning(CleanOptions{TrackDirtyTables: true}))
+
+	// Tables created after startup are tracked by the event trigger
+	_, err := tc.Pool.Exec(ctx, `
+		CREATE TABLE touched (id INT PRIMARY KEY);
+		CREATE TABLE untouched (id INT PRIMARY KEY);
+		INSERT INTO touched VALUES (1);
+		INSERT INTO untouched VALUES (1);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	if err := tc.CleanAllTables(ctx); err != nil {
+		t.Fatalf("Failed to clean all tables: %v", err)
+	}
+	dirty, err := tc.DirtyTables(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read dirty tables: %v", err)
+	}
+	if len(dirty) != 0 {
+		t.Errorf("Expected no dirty tables after cleaning, got %v", dirty)
+	}
+
+	if _, err := tc.Pool.Exec(ctx, "INSERT INTO touched VALUES (2)"); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	dirty, err = tc.DirtyTables(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read dirty tables: %v", err)
+	}
+	if !slices.Equal(dirty, []string{"public.touched"}) {
+		t.Errorf("Expected only public.touched to be dirty, got %v", dirty)
+	}
+
+	// Write to untouched without leaving a mark, so cleaning must skip it
+	_, err = tc.Pool.Exec(ctx, `
+		INSERT INTO untouched VALUES (2);
+		DELETE FROM _pgtc.dirty_tables WHERE relid = 'untouched'::regclass;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to write untracked row: %v", err)
+	}
+
+	if err := tc.CleanAllTables(ctx); err != nil {
+		t.Fatalf("Failed to clean all tables: %v", err)
+	}
+	if count := countRows(t, tc, "touched"); count != 0 {
+		t.Errorf("Expected touched to be empty, got %d rows", count)
+	}
+	if count := countRows(t, tc, "untouched"); count != 1 {
+		t.Errorf("Expected untouched to be skipped, got %d rows", count)
+	}
+}
+
+func TestDirtyTableTracking_SchemaDump(t *testing.T) {
+	tc := New(t, WithImageFamily(ImageFamilyPostgres, ""), WithCleaning(CleanOptions{TrackDirtyTables: true}))
+
+	if _, err := tc.Pool.Exec(context.Background(), "CREATE TABLE widgets (id INT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	dump, err := tc.SchemaDump(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to dump schema: %v", err)
+	}
+	if strings.Contains(dump, trackingSchema) {
+		t.Errorf("Expected the schema dump to leave out tracking objects, got:\n%s", dump)
+	}
+}
@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTrackingSQL(t *testing.T) {
+	for _, want := range []string{
+		"CREATE SCHEMA IF NOT EXISTS " + trackingSchema + ";",
+		"CREATE TRIGGER " + trackingTrigger + " ",
+		"DROP TRIGGER IF EXISTS " + trackingTrigger + " ",
+		trackingSchema + ".dirty_tables",
+	} {
+		if !strings.Contains(trackingSQL, want) {
+			t.Errorf("Expected tracking SQL to contain %q", want)
+		}
+	}
+}
+
+func TestTrackingSQL_Statements(t *testing.T) {
+	// Dollar-quoted function bodies must not be split
+	stmts, err := splitSQL(trackingSQL)
+	if err != nil {
+		t.Fatalf("Expected tracking SQL to parse, got %v", err)
+	}
+	if len(stmts) != 9 {
+		t.Errorf("Expected 9 statements, got %d", len(stmts))
+	}
+}
+
+func TestUserObjectFilter_ExcludesTracking(t *testing.T) {
+	if !strings.Contains(userObjectFilter, "'"+trackingSchema+"'") {
+		t.Errorf("Expected the schema snapshot to leave out the %s schema", trackingSchema)
+	}
+}
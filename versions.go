@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// MigrateTo migrates the container's database up or down to version.
+// It uses the configured golang-migrate migrations (MigrationsFS or MigrationsPath).
+func (tc *PostgreSQLTestContainer) MigrateTo(ctx context.Context, version uint) error {
+	return tc.withMigrate(ctx, func(m *migrate.Migrate) error {
+		return m.Migrate(version)
+	})
+}
+
+// MigrateUp applies the next steps migrations. It fails if fewer than steps are pending.
+func (tc *PostgreSQLTestContainer) MigrateUp(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("%w: steps must be at least 1, got %d", ErrMigrationsFailed, steps)
+	}
+	return tc.withMigrate(ctx, func(m *migrate.Migrate) error {
+		return m.Steps(steps)
+	})
+}
+
+// MigrateDown reverts the last steps migrations. It fails if fewer than steps are applied.
+func (tc *PostgreSQLTestContainer) MigrateDown(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("%w: steps must be at least 1, got %d", ErrMigrationsFailed, steps)
+	}
+	return tc.withMigrate(ctx, func(m *migrate.Migrate) error {
+		return m.Steps(-steps)
+	})
+}
+
+// MigrationVersion returns the current migration version and whether the last migration failed
+// part way (dirty). The version is 0 when no migration has been applied.
+func (tc *PostgreSQLTestContainer) MigrationVersion(ctx context.Context) (version uint, dirty bool, err error) {
+	err = tc.withMigrate(ctx, func(m *migrate.Migrate) error {
+		var versionErr error
+		version, dirty, versionErr = m.Version()
+		if errors.Is(versionErr, migrate.ErrNilVersion) {
+			return nil
+		}
+		return versionErr
+	})
+	return version, dirty, err
+}
+
+// withMigrate runs fn with a golang-migrate instance for the container's database.
+// ErrNoChange is not an error, and cancelling ctx stops after the running migration.
+func (tc *PostgreSQLTestContainer) withMigrate(ctx context.Context, fn func(m *migrate.Migrate) error) error {
+	source, err := tc.golangMigrateSource("")
+	if err != nil {
+		return err
+	}
+
+	m, err := newMigrate(source, tc.DatabaseURL, tc.logf)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMigrationsFailed, err)
+	}
+	defer closeMigrate(m, tc.logf)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			m.GracefulStop <- true
+		case <-done:
+		}
+	}()
+
+	if err := fn(m); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("%w: %v", ErrMigrationsFailed, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMigrateTo_DataMigration(t *testing.T) {
+	ctx := context.Background()
+
+	config := DefaultPostgreSQLConfig()
+	config.MigrationsPath = writeMigrations(t, map[string]string{
+		"001_users.up.sql":        "CREATE TABLE users (id SERIAL PRIMARY KEY, full_name TEXT NOT NULL);",
+		"001_users.down.sql":      "DROP TABLE users;",
+		"002_split_name.up.sql":   "ALTER TABLE users ADD COLUMN first_name TEXT, ADD COLUMN last_name TEXT; UPDATE users SET first_name = split_part(full_name, ' ', 1), last_name = split_part(full_name, ' ', 2); ALTER TABLE users DROP COLUMN full_name;",
+		"002_split_name.down.sql": "ALTER TABLE users ADD COLUMN full_name TEXT; UPDATE users SET full_name = first_name || ' ' || last_name; ALTER TABLE users DROP COLUMN first_name, DROP COLUMN last_name;",
+	})
+	tc := New(t, WithConfig(config))
+
+	version, dirty, err := tc.MigrationVersion(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read migration version: %v", err)
+	}
+	if version != 0 || dirty {
+		t.Errorf("Expected version 0 and clean before migrating, got %d (dirty=%t)", version, dirty)
+	}
+
+	if err := tc.MigrateTo(ctx, 1); err != nil {
+		t.Fatalf("Failed to migrate to version 1: %v", err)
+	}
+	if _, err := tc.Pool.Exec(ctx, "INSERT INTO users (full_name) VALUES ('Ada Lovelace')"); err != nil {
+		t.Fatalf("Failed to insert legacy row: %v", err)
+	}
+
+	if err := tc.MigrateUp(ctx, 1); err != nil {
+		t.Fatalf("Failed to migrate up: %v", err)
+	}
+
+	var first, last string
+	if err := tc.Pool.QueryRow(ctx, "SELECT first_name, last_name FROM users").Scan(&first, &last); err != nil {
+		t.Fatalf("Failed to read migrated row: %v", err)
+	}
+	if first != "Ada" || last != "Lovelace" {
+		t.Errorf("Expected Ada Lovelace to be split, got %q %q", first, last)
+	}
+
+	if err := tc.MigrateDown(ctx, 1); err != nil {
+		t.Fatalf("Failed to migrate down: %v", err)
+	}
+	version, _, err = tc.MigrationVersion(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read migration version: %v", err)
+	}
+	if version != 1 {
+		t.Errorf("Expected version 1 after migrating down, got %d", version)
+	}
+
+	if err := tc.MigrateUp(ctx, 5); err == nil {
+		t.Error("Expected error when fewer migrations are pending than requested")
+	}
+}
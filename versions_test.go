@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMigrateSteps_Invalid(t *testing.T) {
+	tc := &PostgreSQLTestContainer{config: DefaultPostgreSQLConfig()}
+	ctx := context.Background()
+
+	if err := tc.MigrateUp(ctx, 0); !errors.Is(err, ErrMigrationsFailed) {
+		t.Errorf("Expected ErrMigrationsFailed for zero steps up, got %v", err)
+	}
+	if err := tc.MigrateDown(ctx, -1); !errors.Is(err, ErrMigrationsFailed) {
+		t.Errorf("Expected ErrMigrationsFailed for negative steps down, got %v", err)
+	}
+}
+
+func TestMigrateTo_RequiresGolangMigrate(t *testing.T) {
+	config := DefaultPostgreSQLConfig()
+	config.Migrator = NewSQLFilesMigrator(fstest.MapFS{}, ".")
+	tc := &PostgreSQLTestContainer{config: config}
+
+	if err := tc.MigrateTo(context.Background(), 1); !errors.Is(err, ErrMigrationsFailed) {
+		t.Errorf("Expected ErrMigrationsFailed for a non golang-migrate migrator, got %v", err)
+	}
+}